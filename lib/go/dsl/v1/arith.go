@@ -0,0 +1,268 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrOverflow is raised, if an integer operation exceeds the int64 value range. Operations which cannot return
+// an error panic with an error which wraps ErrOverflow, so that the execution environment can inspect it
+// using errors.Is.
+var ErrOverflow = errors.New("int64 overflow")
+
+// BinaryOp is an enum like type to identify an arithmetic operation between two series.
+// See also Points.Combine and Group.Combine.
+type BinaryOp int
+
+// Valid determines if BinaryOp defines a valid enum.
+// See also OpAdd, OpSub, OpMul and OpDiv.
+func (o BinaryOp) Valid() bool {
+	return o >= OpAdd && o <= OpDiv
+}
+
+const (
+	// OpAdd calculates left + right.
+	OpAdd BinaryOp = iota + 1
+
+	// OpSub calculates left - right.
+	OpSub
+
+	// OpMul calculates left * right.
+	OpMul
+
+	// OpDiv calculates left / right using the configured RoundingMode.
+	OpDiv
+)
+
+// JoinMode is an enum like type which defines how two series are aligned by their X values before a BinaryOp
+// is applied to the according Y values.
+type JoinMode int
+
+// Valid determines if JoinMode defines a valid enum.
+// See also InnerJoin, OuterJoin and FillForwardJoin.
+func (j JoinMode) Valid() bool {
+	return j >= InnerJoin && j <= FillForwardJoin
+}
+
+const (
+	// InnerJoin only returns points whose X exists in both series.
+	InnerJoin JoinMode = iota + 1
+
+	// OuterJoin returns points for all X of both series. A missing Y is replaced by the configured fill value.
+	OuterJoin
+
+	// FillForwardJoin returns points for all X of both series. A missing Y is replaced by the last known Y of the
+	// same series. If there is no preceding point, the configured fill value is used.
+	FillForwardJoin
+)
+
+// RoundingMode is an enum like type which defines how the result of an integer division is rounded. The zero value
+// is not valid and is treated like RoundDown by Div, but ArithOptions resolves it to RoundHalfUp.
+type RoundingMode int
+
+// Valid determines if RoundingMode defines a valid enum.
+// See also RoundDown, RoundUp, RoundFloor, RoundCeiling, RoundHalfUp and RoundHalfEven.
+func (r RoundingMode) Valid() bool {
+	return r >= RoundDown && r <= RoundHalfEven
+}
+
+const (
+	// RoundDown truncates towards zero, just like the Go integer division.
+	RoundDown RoundingMode = iota + 1
+
+	// RoundUp rounds away from zero.
+	RoundUp
+
+	// RoundFloor rounds towards negative infinity.
+	RoundFloor
+
+	// RoundCeiling rounds towards positive infinity.
+	RoundCeiling
+
+	// RoundHalfUp rounds towards the nearest neighbor and if both are equidistant, away from zero.
+	// This is the commercial rounding.
+	RoundHalfUp
+
+	// RoundHalfEven rounds towards the nearest neighbor and if both are equidistant, towards the even neighbor.
+	// This is also known as banker's rounding.
+	RoundHalfEven
+)
+
+// Div returns a / b rounded according to the mode. Returns false, if b is zero or if the result overflows, which is
+// only the case for math.MinInt64 / -1. Invalid modes are treated like RoundDown.
+func (r RoundingMode) Div(a, b int64) (int64, bool) {
+	if b == 0 || (a == math.MinInt64 && b == -1) {
+		return 0, false
+	}
+
+	q := a / b
+	rem := a % b
+	if rem == 0 {
+		return q, true
+	}
+
+	// the sign of the exact result, which is never zero here, because there is a remainder
+	negative := (a < 0) != (b < 0)
+
+	// compare 2*|rem| against |b| without overflowing
+	absRem := uint64(rem)
+	if rem < 0 {
+		absRem = uint64(-rem)
+	}
+
+	absB := uint64(b)
+	if b < 0 {
+		absB = uint64(-b)
+	}
+
+	half := 0
+	switch {
+	case absRem > absB-absRem:
+		half = 1
+	case absRem < absB-absRem:
+		half = -1
+	}
+
+	if !r.awayFromZero(negative, half, q%2 != 0) {
+		return q, true
+	}
+
+	if negative {
+		return q - 1, true
+	}
+
+	return q + 1, true
+}
+
+// awayFromZero decides if a truncated quotient with a non-zero remainder must be rounded away from zero.
+// The half parameter is the result of comparing the remainder against the half of the divisor.
+// Invalid modes are treated like RoundDown.
+func (r RoundingMode) awayFromZero(negative bool, half int, oddQuotient bool) bool {
+	switch r {
+	case RoundUp:
+		return true
+	case RoundFloor:
+		return negative
+	case RoundCeiling:
+		return !negative
+	case RoundHalfUp:
+		return half >= 0
+	case RoundHalfEven:
+		return half > 0 || (half == 0 && oddQuotient)
+	default:
+		return false
+	}
+}
+
+// ArithOptions configures how two series are combined by Points.Combine and Group.Combine.
+//
+// Scales are interpreted like ScaleOf, so a stored Y value means Y/Scale. If an operand scale is zero, it is treated
+// as 1. If the result Scale is zero, the larger of both operand scales is used, see also ResultScale. Before the
+// operation is applied, both operands are rescaled from LeftScale and RightScale into the result scale. Use
+// WithScales to load the scales of the according metrics.
+type ArithOptions struct {
+	// Join defines how the X values of both series are aligned. The zero value is treated as InnerJoin.
+	Join JoinMode
+
+	// Fill is used as Y value for a missing point when using OuterJoin or FillForwardJoin.
+	Fill int64
+
+	// LeftScale is the scale of the left operand.
+	LeftScale int64
+
+	// RightScale is the scale of the right operand.
+	RightScale int64
+
+	// Scale is the scale of the result. The zero value is treated as the larger of LeftScale and RightScale.
+	Scale int64
+
+	// Rounding is applied whenever a division or a rescale to a smaller scale is required. The zero value is
+	// treated as RoundHalfUp.
+	Rounding RoundingMode
+}
+
+// WithScales returns a copy of the options with LeftScale and RightScale loaded using ScaleOf for the given metrics.
+// If the result Scale is unset, the larger of both is used, so that no precision is lost.
+func (o ArithOptions) WithScales(db DB, leftMetricID, rightMetricID UUID) ArithOptions {
+	o.LeftScale = db.ScaleOf(leftMetricID)
+	o.RightScale = db.ScaleOf(rightMetricID)
+	o.Scale = o.ResultScale()
+
+	return o
+}
+
+// ResultScale returns the scale of the result, which is Scale or, if unset, the larger of LeftScale and RightScale,
+// so that no precision is lost. Example, to calculate an efficiency from two series with a scale of 10:
+//  out.Div(in, ArithOptions{LeftScale: 10, RightScale: 10}) // result scale is 10
+func (o ArithOptions) ResultScale() int64 {
+	if o.Scale > 0 {
+		return o.Scale
+	}
+
+	return maxScale(o.LeftScale, o.RightScale)
+}
+
+// resolved returns a copy of the options with the result Scale and the Rounding set, as passed to the intrinsics.
+func (o ArithOptions) resolved() ArithOptions {
+	o.Scale = o.ResultScale()
+	if o.Rounding == 0 {
+		o.Rounding = RoundHalfUp
+	}
+
+	return o
+}
+
+// Combine aligns this series (left) with the other series (right) by X as defined by the JoinMode and applies
+// the given operation to each pair of Y values. Both series must be sorted ascending by X, otherwise the result
+// is undefined. The result is sorted ascending by X.
+//
+// All calculations are performed using integer arithmetics. If an intermediate or final value overflows,
+// a panic with an error wrapping ErrOverflow is raised. A division by zero omits the according point.
+func (p Points) Combine(other Points, op BinaryOp, opts ArithOptions) Points {
+	return Math.Combine(p, other, op, opts.resolved())
+}
+
+// Add is a shortcut for Combine using OpAdd.
+func (p Points) Add(other Points, opts ArithOptions) Points {
+	return p.Combine(other, OpAdd, opts)
+}
+
+// Sub is a shortcut for Combine using OpSub, e.g. to calculate net = production - consumption.
+func (p Points) Sub(other Points, opts ArithOptions) Points {
+	return p.Combine(other, OpSub, opts)
+}
+
+// Mul is a shortcut for Combine using OpMul.
+func (p Points) Mul(other Points, opts ArithOptions) Points {
+	return p.Combine(other, OpMul, opts)
+}
+
+// Div is a shortcut for Combine using OpDiv, e.g. to calculate efficiency = output / input.
+func (p Points) Div(other Points, opts ArithOptions) Points {
+	return p.Combine(other, OpDiv, opts)
+}
+
+// Combine folds all series of the group from left to right using Points.Combine. Example using OpAdd:
+//  [a, b, c] => (a + b) + c
+// All series must share the same scale, which is taken from LeftScale. RightScale is ignored, so an unset result
+// Scale is LeftScale. An empty group returns an empty series and a group of one series returns that series rescaled.
+func (p Group) Combine(op BinaryOp, opts ArithOptions) Points {
+	opts.RightScale = opts.LeftScale
+	return Math.GroupCombine(p, op, opts.resolved())
+}
+
+// Align aligns all series of the group to a common X axis as defined by the JoinMode, so that the Y values at the
+// same index of each series belong together, e.g. to calculate derived metrics like a capacity factor from power
+// and availability. Example using OuterJoin and a fill of 0:
+//  a: [(0|1), (600|2)]
+//  b: [(600|3), (1200|4)]
+//  => a: [(0|1), (600|2), (1200|0)]
+//  => b: [(0|0), (600|3), (1200|4)]
+// The zero value of join is treated as InnerJoin. All series must be sorted strictly ascending by X, otherwise the
+// result is undefined. The result contains exactly one series per input series in the same order.
+func (p Group) Align(join JoinMode, fill int64) Group {
+	return Math.Align(p, join, fill)
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoundingMode_Div(t *testing.T) {
+	tests := []struct {
+		name string
+		mode RoundingMode
+		a, b int64
+		want int64
+		ok   bool
+	}{
+		{"exact", RoundHalfUp, 10, 5, 2, true},
+		{"down-pos", RoundDown, 7, 2, 3, true},
+		{"down-neg", RoundDown, -7, 2, -3, true},
+		{"up-pos", RoundUp, 7, 3, 3, true},
+		{"up-neg", RoundUp, -7, 3, -3, true},
+		{"floor-pos", RoundFloor, 7, 2, 3, true},
+		{"floor-neg", RoundFloor, -7, 2, -4, true},
+		{"ceiling-pos", RoundCeiling, 7, 2, 4, true},
+		{"ceiling-neg", RoundCeiling, 7, -2, -3, true},
+		{"half-up-tie", RoundHalfUp, 5, 2, 3, true},
+		{"half-up-tie-neg", RoundHalfUp, -5, 2, -3, true},
+		{"half-up-below", RoundHalfUp, 4, 3, 1, true},
+		{"half-up-above", RoundHalfUp, 5, 3, 2, true},
+		{"half-even-tie-odd", RoundHalfEven, 5, 2, 2, true},
+		{"half-even-tie-even", RoundHalfEven, 7, 2, 4, true},
+		{"half-even-tie-neg", RoundHalfEven, -7, 2, -4, true},
+		{"half-even-min", RoundHalfEven, math.MinInt64, math.MinInt64, 1, true},
+		{"half-up-min-divisor", RoundHalfUp, math.MaxInt64, math.MinInt64, -1, true},
+		{"div-zero", RoundHalfUp, 1, 0, 0, false},
+		{"overflow", RoundDown, math.MinInt64, -1, 0, false},
+		{"zero-mode", 0, 5, 2, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.mode.Div(tt.a, tt.b)
+			if ok != tt.ok {
+				t.Fatalf("Div() ok = %v, want %v", ok, tt.ok)
+			}
+
+			if got != tt.want {
+				t.Errorf("Div() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArithOptions_ResultScale(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ArithOptions
+		want int64
+	}{
+		{"unset", ArithOptions{}, 1},
+		{"same", ArithOptions{LeftScale: 10, RightScale: 10}, 10},
+		{"left", ArithOptions{LeftScale: 1000, RightScale: 10}, 1000},
+		{"right", ArithOptions{LeftScale: 10, RightScale: 100}, 100},
+		{"explicit", ArithOptions{LeftScale: 10, RightScale: 100, Scale: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.ResultScale(); got != tt.want {
+				t.Errorf("ResultScale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArithOptions_Resolved(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ArithOptions
+		want RoundingMode
+	}{
+		{"unset", ArithOptions{}, RoundHalfUp},
+		{"explicit", ArithOptions{Rounding: RoundDown}, RoundDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.resolved().Rounding; got != tt.want {
+				t.Errorf("resolved().Rounding = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// BucketGroupType describes the type of group of buckets.
+type BucketGroupType string
+
+// BucketGroupOther is a group of buckets with an arbitrary meaning, like a portfolio.
+const BucketGroupOther BucketGroupType = "other"
+
+// A BucketGroup is a collection of buckets with an arbitrary meaning, like a portfolio of wind turbines.
+// See also DB.BucketGroup.
+type BucketGroup struct {
+	ID           UUID                   `json:"id"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	Type         BucketGroupType        `json:"type"`
+	Buckets      UUIDs                  `json:"buckets"`
+	Translations map[string]Translation `json:"translations"`
+}
+
+// LanguageTags returns an alphabetically sorted list of available translations.
+// See also MatchLanguage.
+func (g BucketGroup) LanguageTags() []string {
+	return sortedLangTags(g.Translations)
+}
+
+// Translated is a getter for Translations.
+func (g BucketGroup) Translated() map[string]Translation {
+	return g.Translations
+}
+
+// String returns the name.
+func (g BucketGroup) String() string {
+	return g.Name
+}
+
+// A BucketQuery selects buckets by their attributes and group memberships. All conditions must match and the
+// zero value matches all buckets. See also DB.FindBuckets.
+type BucketQuery struct {
+	// XAttr contains the key/value pairs which must all be contained in the XAttr of a bucket.
+	XAttr map[string]string
+
+	// GroupType restricts the result to members of any group of that type.
+	GroupType BucketGroupType
+
+	// GroupIDs restricts the result to members of any of the given groups.
+	GroupIDs UUIDs
+}
+
+// MatchXAttr returns true, if the given attributes contain all key/value pairs of the query as strings.
+// Implementations of DB.FindBuckets may use it, if they cannot query the attributes natively.
+func (q BucketQuery) MatchXAttr(x XAttr) bool {
+	for key, want := range q.XAttr {
+		if got, ok := x.String(key); !ok || got != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GroupMembers resolves the given group into the identifiers of its member buckets, so that a kernel can take
+// a single portfolio ID as a parameter. Panics with a 404, if no such group exists. Example:
+//  devices := GroupMembers(ctx, req.PortfolioID)
+//  g := Query(ctx).FindSeriesInRange(devices, metric, r)
+func GroupMembers(ctx context.Context, groupID UUID) UUIDs {
+	g, ok := Query(ctx).BucketGroup(groupID)
+	if !ok {
+		panic(httpError{status: http.StatusNotFound, msg: fmt.Sprintf("bucket group %v not found", groupID)})
+	}
+
+	return g.Buckets
+}
+
+// BucketGroupNames translates the given bucket groups identified by their identifiers, if possible.
+// See also BucketNames.
+func BucketGroupNames(ctx context.Context, groupIDs []UUID) []string {
+	db := Query(ctx)
+	return translateNames(ctx, groupIDs, func(uuid UUID) (translatedEntity, bool) {
+		return db.BucketGroup(uuid)
+	})
+}
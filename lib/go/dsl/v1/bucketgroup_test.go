@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"testing"
+)
+
+func TestBucketQuery_MatchXAttr(t *testing.T) {
+	x := XAttr{"site": "north", "vendor": "enercon", "rating": 3000}
+
+	tests := []struct {
+		name  string
+		query BucketQuery
+		want  bool
+	}{
+		{"empty", BucketQuery{}, true},
+		{"single", BucketQuery{XAttr: map[string]string{"site": "north"}}, true},
+		{"all", BucketQuery{XAttr: map[string]string{"site": "north", "vendor": "enercon"}}, true},
+		{"mismatch", BucketQuery{XAttr: map[string]string{"site": "south"}}, false},
+		{"missing", BucketQuery{XAttr: map[string]string{"park": "north"}}, false},
+		{"non-string", BucketQuery{XAttr: map[string]string{"rating": "3000"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.MatchXAttr(x); got != tt.want {
+				t.Errorf("MatchXAttr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	db := newTestDB()
+	portfolio, a, b := NewUUID(), NewUUID(), NewUUID()
+	db.groups[portfolio] = BucketGroup{
+		ID:           portfolio,
+		Name:         "portfolio",
+		Type:         BucketGroupOther,
+		Buckets:      UUIDs{a, b},
+		Translations: map[string]Translation{"de": {Name: "Portfolio DE"}},
+	}
+
+	ctx := testContext(db, map[string]string{"Accept-Language": "de"})
+	if got := GroupMembers(ctx, portfolio); len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("unexpected members %v", got)
+	}
+
+	if got := BucketGroupNames(ctx, []UUID{portfolio}); got[0] != "Portfolio DE" {
+		t.Errorf("unexpected name %v", got[0])
+	}
+
+	defer func() {
+		err, ok := recover().(httpError)
+		if !ok || err.Status() != 404 {
+			t.Errorf("expected 404 but got %v", err)
+		}
+	}()
+
+	GroupMembers(ctx, NewUUID())
+}
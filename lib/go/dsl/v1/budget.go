@@ -0,0 +1,344 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBudgetExceeded is the cause of the panic raised by Checkpoint or by any DB query returned by Query, if a
+// kernel has loaded more points or series than allowed by its Budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// A Budget limits the resources a kernel may consume, so that a runaway kernel cannot burn CPU and memory
+// forever. A zero value of a field means unlimited. See also WithBudget.
+type Budget struct {
+	// MaxPoints is the maximum amount of points which may be loaded in total by all DB queries. The exact amount
+	// is only known after each query, so a query is refused up front, if its points, estimated from DB.MinMax and
+	// the sampling period of the Descriptor, exceed the remaining budget. Metrics without a constant period cannot
+	// be estimated, and their queries may still allocate an oversized result before the budget is checked.
+	MaxPoints int64
+
+	// MaxSeries is the maximum amount of series which may be loaded in total by all DB queries.
+	MaxSeries int64
+
+	// MaxWallTime is the maximum duration of the evaluation, after which the context is canceled.
+	MaxWallTime time.Duration
+}
+
+// Usage contains the amount of resources consumed so far. See also UsageOf.
+type Usage struct {
+	Points int64 `json:"points"`
+	Series int64 `json:"series"`
+}
+
+// budgetState is the shared and mutable accounting of a Budget. The parent is the budget of the enclosing
+// context, which is charged and checked as well.
+type budgetState struct {
+	budget Budget
+	parent *budgetState
+	points int64
+	series int64
+}
+
+// WithBudget annotates a new Context with the given budget. If a MaxWallTime is set, the returned context has
+// an according deadline. The returned cancel function must always be called, just like for context.WithTimeout.
+// Budgets can be nested but a nested budget can only restrict further: the usage is accounted to all enclosing
+// budgets and the limits of all of them are enforced.
+// Example:
+//  ctx, cancel := WithBudget(ctx, Budget{MaxPoints: 50_000_000, MaxWallTime: 30 * time.Second})
+//  defer cancel()
+func WithBudget(ctx context.Context, b Budget) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if b.MaxWallTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, b.MaxWallTime)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	parent, _ := ctx.Value(ctxBudget).(*budgetState)
+
+	return context.WithValue(ctx, ctxBudget, &budgetState{budget: b, parent: parent}), cancel
+}
+
+// UsageOf returns the resources consumed so far by the DB queries within the budget of the context. Returns
+// the zero value, if the context has no budget.
+func UsageOf(ctx context.Context) Usage {
+	state, ok := ctx.Value(ctxBudget).(*budgetState)
+	if !ok {
+		return Usage{}
+	}
+
+	return Usage{Points: atomic.LoadInt64(&state.points), Series: atomic.LoadInt64(&state.series)}
+}
+
+// Checkpoint panics, if the evaluation must be aborted, so that the execution environment can respond with an
+// according problem: a 504, if the deadline has been exceeded, a 503, if the context has been canceled, e.g.
+// because the client has disconnected, and a 422, if the budget has been exceeded. All DB queries returned by
+// Query perform a checkpoint automatically, and so do Group.ForEachContext, SeriesGroup.ForEachContext,
+// SeriesGroup.Pivot, the writes of a Stream, the reference execution of a Plan and the long-running intrinsics
+// Points.RollingContext, Points.OutliersContext and Points.ForecastContext. A kernel should also call it
+// regularly within its own long-running loops.
+//
+// A checkpoint cannot interrupt a call which is already running inside the DB or an intrinsic without a context,
+// like Points.Rolling. The evaluation is only aborted at the next checkpoint after such a call has returned, so
+// a kernel should rather use the context variants or process a long interval in chunks, e.g. by using DB.Cursor.
+// Example:
+//  for _, id := range devices {
+//    Checkpoint(ctx)
+//    ...
+//  }
+func Checkpoint(ctx context.Context) {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		panic(httpError{status: http.StatusGatewayTimeout, msg: "kernel deadline exceeded", cause: err})
+	case err != nil:
+		panic(httpError{status: http.StatusServiceUnavailable, msg: "kernel evaluation canceled", cause: err})
+	}
+
+	state, _ := ctx.Value(ctxBudget).(*budgetState)
+	for ; state != nil; state = state.parent {
+		state.check()
+	}
+}
+
+// check panics, if the usage exceeds the limits of this budget.
+func (b *budgetState) check() {
+	if max := b.budget.MaxPoints; max > 0 {
+		if points := atomic.LoadInt64(&b.points); points > max {
+			panic(httpError{
+				status: http.StatusUnprocessableEntity,
+				msg:    fmt.Sprintf("kernel loaded %d points but the budget allows only %d", points, max),
+				cause:  ErrBudgetExceeded,
+			})
+		}
+	}
+
+	if max := b.budget.MaxSeries; max > 0 {
+		if series := atomic.LoadInt64(&b.series); series > max {
+			panic(httpError{
+				status: http.StatusUnprocessableEntity,
+				msg:    fmt.Sprintf("kernel loaded %d series but the budget allows only %d", series, max),
+				cause:  ErrBudgetExceeded,
+			})
+		}
+	}
+}
+
+// remainingPoints returns the least amount of points which may still be loaded within this and all enclosing
+// budgets or false, if the amount of points is unlimited.
+func (b *budgetState) remainingPoints() (int64, bool) {
+	var remaining int64
+	limited := false
+	for ; b != nil; b = b.parent {
+		if max := b.budget.MaxPoints; max > 0 {
+			if r := max - atomic.LoadInt64(&b.points); !limited || r < remaining {
+				remaining = r
+				limited = true
+			}
+		}
+	}
+
+	return remaining, limited
+}
+
+// spend accounts the given amount of points and series to the budget of the context and all enclosing budgets,
+// if any, and performs a Checkpoint.
+func spend(ctx context.Context, points, series int64) {
+	state, _ := ctx.Value(ctxBudget).(*budgetState)
+	for ; state != nil; state = state.parent {
+		atomic.AddInt64(&state.points, points)
+		atomic.AddInt64(&state.series, series)
+	}
+
+	Checkpoint(ctx)
+}
+
+// guardedDB performs a Checkpoint before each query and accounts the loaded points and series afterwards. Queries
+// of raw points are refused up front, if their estimated points exceed the budget. See also Query.
+type guardedDB struct {
+	ctx context.Context
+	db  DB
+}
+
+func (g guardedDB) Bucket(id UUID) (Bucket, bool) {
+	Checkpoint(g.ctx)
+	return g.db.Bucket(id)
+}
+
+func (g guardedDB) BucketGroup(id UUID) (BucketGroup, bool) {
+	Checkpoint(g.ctx)
+	return g.db.BucketGroup(id)
+}
+
+func (g guardedDB) BucketGroups(groupType BucketGroupType) []BucketGroup {
+	Checkpoint(g.ctx)
+	return g.db.BucketGroups(groupType)
+}
+
+func (g guardedDB) FindBuckets(q BucketQuery) []Bucket {
+	Checkpoint(g.ctx)
+	return g.db.FindBuckets(q)
+}
+
+func (g guardedDB) Metric(id UUID) (Metric, bool) {
+	Checkpoint(g.ctx)
+	return g.db.Metric(id)
+}
+
+func (g guardedDB) Descriptor(metricID UUID) (Descriptor, bool) {
+	Checkpoint(g.ctx)
+	return g.db.Descriptor(metricID)
+}
+
+func (g guardedDB) ScaleOf(metricID UUID) int64 {
+	Checkpoint(g.ctx)
+	return g.db.ScaleOf(metricID)
+}
+
+func (g guardedDB) FindRanges(bucketIDs []UUID) []DataRange {
+	Checkpoint(g.ctx)
+	return g.db.FindRanges(bucketIDs)
+}
+
+func (g guardedDB) MinMax(bucketID, metricID UUID) DataRange {
+	Checkpoint(g.ctx)
+	return g.db.MinMax(bucketID, metricID)
+}
+
+func (g guardedDB) FindInRange(bucketIDs []UUID, metricID UUID, r Interval) Group {
+	Checkpoint(g.ctx)
+	g.reserve(bucketIDs, []UUID{metricID}, r)
+	res := g.db.FindInRange(bucketIDs, metricID, r)
+	var points int64
+	for _, pts := range res {
+		points += int64(len(pts))
+	}
+
+	spend(g.ctx, points, int64(len(res)))
+	return res
+}
+
+func (g guardedDB) FindSeriesInRange(bucketIDs []UUID, metricID UUID, r Interval) SeriesGroup {
+	Checkpoint(g.ctx)
+	g.reserve(bucketIDs, []UUID{metricID}, r)
+	return g.spendSeries(g.db.FindSeriesInRange(bucketIDs, metricID, r))
+}
+
+func (g guardedDB) FindAllInRange(bucketIDs []UUID, metricIDs []UUID, r Interval) SeriesGroup {
+	Checkpoint(g.ctx)
+	g.reserve(bucketIDs, metricIDs, r)
+	return g.spendSeries(g.db.FindAllInRange(bucketIDs, metricIDs, r))
+}
+
+// Execute accounts the points of the result, because a pushed down plan does not load the raw points. If the
+// DB has no pushdown, the plan is evaluated using this guarded DB, so that the raw points are accounted instead.
+func (g guardedDB) Execute(plan Plan) SeriesGroup {
+	Checkpoint(g.ctx)
+	if res := g.db.Execute(plan); res != nil {
+		return g.spendSeries(res)
+	}
+
+	return plan.eval(g.ctx, g)
+}
+
+// Cursor accounts a series per bucket immediately and the points of each chunk when it is loaded.
+func (g guardedDB) Cursor(bucketIDs []UUID, metricID UUID, r Interval, opts CursorOptions) Cursor {
+	Checkpoint(g.ctx)
+	c := g.db.Cursor(bucketIDs, metricID, r, opts)
+	spend(g.ctx, 0, int64(len(bucketIDs)))
+	return guardedCursor{ctx: g.ctx, cursor: c}
+}
+
+// reserve panics, if the estimated amount of points of a query exceeds the remaining budget, so that an oversized
+// query is refused before the DB loads it into memory. The estimation assumes a point per sampling period within
+// the intersection of the interval and the data range of each series. Series of metrics without a constant
+// period are not estimated.
+func (g guardedDB) reserve(bucketIDs []UUID, metricIDs []UUID, r Interval) {
+	state, _ := g.ctx.Value(ctxBudget).(*budgetState)
+	remaining, limited := state.remainingPoints()
+	if !limited {
+		return
+	}
+
+	var estimate int64
+	for _, metricID := range metricIDs {
+		desc, ok := g.db.Descriptor(metricID)
+		if !ok {
+			continue
+		}
+
+		period, ok := desc.Sampling.Period.Seconds()
+		if !ok || period <= 0 {
+			continue
+		}
+
+		for _, bucketID := range bucketIDs {
+			dr := g.db.MinMax(bucketID, metricID)
+			if !dr.Valid {
+				continue
+			}
+
+			min, max := dr.MinX, dr.MaxX
+			if min < r.Min {
+				min = r.Min
+			}
+
+			if max > r.Max {
+				max = r.Max
+			}
+
+			if min <= max {
+				estimate += (max-min)/period + 1
+			}
+		}
+	}
+
+	if estimate > remaining {
+		panic(httpError{
+			status: http.StatusUnprocessableEntity,
+			msg:    fmt.Sprintf("kernel would load about %d points but the budget allows only %d more", estimate, remaining),
+			cause:  ErrBudgetExceeded,
+		})
+	}
+}
+
+func (g guardedDB) spendSeries(res SeriesGroup) SeriesGroup {
+	var points int64
+	for _, s := range res {
+		points += int64(len(s.Points))
+	}
+
+	spend(g.ctx, points, int64(len(res)))
+	return res
+}
+
+// guardedCursor performs a Checkpoint before loading each chunk and accounts its points afterwards.
+type guardedCursor struct {
+	ctx    context.Context
+	cursor Cursor
+}
+
+func (c guardedCursor) Next() bool {
+	Checkpoint(c.ctx)
+	if !c.cursor.Next() {
+		return false
+	}
+
+	spend(c.ctx, int64(len(c.cursor.Chunk().Points)), 0)
+	return true
+}
+
+func (c guardedCursor) Chunk() Chunk {
+	return c.cursor.Chunk()
+}
+
+func (c guardedCursor) Close() {
+	c.cursor.Close()
+}
@@ -0,0 +1,312 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// expectStatus invokes f and checks that it panics with a httpError of the given status or does not panic at all,
+// if status is 0.
+func expectStatus(t *testing.T, status int, f func()) {
+	t.Helper()
+
+	defer func() {
+		t.Helper()
+		r := recover()
+		if status == 0 {
+			if r != nil {
+				t.Errorf("unexpected panic %v", r)
+			}
+
+			return
+		}
+
+		err, ok := r.(httpError)
+		if !ok || err.Status() != status {
+			t.Errorf("expected status %d but got %v", status, r)
+		}
+	}()
+
+	f()
+}
+
+func TestCheckpoint(t *testing.T) {
+	db := newTestDB()
+	a, b, metric := NewUUID(), NewUUID(), NewUUID()
+	db.put(a, metric, Points{{X: 1, Y: 1}, {X: 2, Y: 2}})
+	db.put(b, metric, Points{{X: 1, Y: 1}})
+	r := Interval{Min: 0, Max: 10}
+
+	tests := []struct {
+		name   string
+		budget Budget
+		query  func(db DB)
+		status int
+	}{
+		{
+			name:   "unlimited",
+			query:  func(db DB) { db.FindInRange([]UUID{a, b}, metric, r) },
+			status: 0,
+		},
+		{
+			name:   "within-points",
+			budget: Budget{MaxPoints: 3},
+			query:  func(db DB) { db.FindSeriesInRange([]UUID{a, b}, metric, r) },
+			status: 0,
+		},
+		{
+			name:   "exceeds-points",
+			budget: Budget{MaxPoints: 2},
+			query:  func(db DB) { db.FindAllInRange([]UUID{a, b}, []UUID{metric}, r) },
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "exceeds-series",
+			budget: Budget{MaxSeries: 1},
+			query:  func(db DB) { db.FindInRange([]UUID{a, b}, metric, r) },
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "exceeds-execute",
+			budget: Budget{MaxPoints: 2},
+			query:  func(db DB) { db.Execute(Select(metric).From(a, b).Between(r).Reduce(SumY)) },
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "within-execute",
+			budget: Budget{MaxPoints: 3},
+			query:  func(db DB) { db.Execute(Select(metric).From(a, b).Between(r).Reduce(SumY)) },
+			status: 0,
+		},
+		{
+			name:   "exceeds-cursor",
+			budget: Budget{MaxPoints: 2},
+			query: func(db DB) {
+				c := db.Cursor([]UUID{a, b}, metric, r, CursorOptions{})
+				defer c.Close()
+				for c.Next() {
+				}
+			},
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := WithBudget(testContext(db, nil), tt.budget)
+			defer cancel()
+
+			expectStatus(t, tt.status, func() {
+				tt.query(Query(ctx))
+			})
+		})
+	}
+}
+
+func TestCheckpoint_Estimate(t *testing.T) {
+	db := newTestDB()
+	a, metric := NewUUID(), NewUUID()
+	db.put(a, metric, Points{{X: 0, Y: 1}, {X: 600, Y: 2}, {X: 1200, Y: 3}})
+	db.descs[metric] = Descriptor{ID: metric, Sampling: SamplingSpec{Type: PeriodStart, Period: Period10m}}
+
+	tests := []struct {
+		name   string
+		budget Budget
+		r      Interval
+		status int
+	}{
+		{
+			name:   "within",
+			budget: Budget{MaxPoints: 3},
+			r:      Interval{Min: 0, Max: 1_000_000},
+			status: 0,
+		},
+		{
+			name:   "clamped",
+			budget: Budget{MaxPoints: 2},
+			r:      Interval{Min: 600, Max: 1_000_000},
+			status: 0,
+		},
+		{
+			name:   "exceeds",
+			budget: Budget{MaxPoints: 2},
+			r:      Interval{Min: 0, Max: 1_000_000},
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := WithBudget(testContext(db, nil), tt.budget)
+			defer cancel()
+
+			expectStatus(t, tt.status, func() {
+				Query(ctx).FindInRange([]UUID{a}, metric, tt.r)
+			})
+
+			if tt.status != 0 && UsageOf(ctx) != (Usage{}) {
+				t.Errorf("expected query to be refused before loading, but got %+v", UsageOf(ctx))
+			}
+		})
+	}
+}
+
+func TestCheckpoint_Canceled(t *testing.T) {
+	ctx, cancel := WithBudget(testContext(newTestDB(), nil), Budget{})
+	cancel()
+
+	expectStatus(t, http.StatusServiceUnavailable, func() {
+		Query(ctx).Bucket(NewUUID())
+	})
+}
+
+func TestCheckpoint_Deadline(t *testing.T) {
+	ctx, cancel := WithBudget(testContext(newTestDB(), nil), Budget{MaxWallTime: time.Nanosecond})
+	defer cancel()
+	<-ctx.Done()
+
+	expectStatus(t, http.StatusGatewayTimeout, func() {
+		Checkpoint(ctx)
+	})
+}
+
+func TestUsageOf(t *testing.T) {
+	db := newTestDB()
+	a, metric := NewUUID(), NewUUID()
+	db.put(a, metric, Points{{X: 1, Y: 1}, {X: 2, Y: 2}})
+
+	ctx, cancel := WithBudget(testContext(db, nil), Budget{})
+	defer cancel()
+
+	q := Query(WithDB(ctx, Query(ctx))) // must not account twice
+	q.FindInRange([]UUID{a}, metric, Interval{Min: 0, Max: 10})
+
+	if got := UsageOf(ctx); got != (Usage{Points: 2, Series: 1}) {
+		t.Errorf("unexpected usage %+v", got)
+	}
+
+	if UsageOf(context.Background()) != (Usage{}) {
+		t.Errorf("expected zero usage without budget")
+	}
+
+	defer func() {
+		err, _ := recover().(httpError)
+		if !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("expected ErrBudgetExceeded but got %v", err)
+		}
+	}()
+
+	ctx, cancel = WithBudget(context.Background(), Budget{MaxPoints: 1})
+	defer cancel()
+	Query(WithDB(ctx, db)).FindInRange([]UUID{a}, metric, Interval{Min: 0, Max: 10})
+}
+
+func TestWithBudget_Nested(t *testing.T) {
+	db := newTestDB()
+	a, metric := NewUUID(), NewUUID()
+	db.put(a, metric, Points{{X: 1, Y: 1}, {X: 2, Y: 2}, {X: 3, Y: 3}})
+	r := Interval{Min: 0, Max: 10}
+
+	tests := []struct {
+		name   string
+		outer  Budget
+		inner  Budget
+		status int
+	}{
+		{"unlimited", Budget{}, Budget{}, 0},
+		{"outer-exceeded", Budget{MaxPoints: 1}, Budget{}, http.StatusUnprocessableEntity},
+		{"inner-exceeded", Budget{}, Budget{MaxPoints: 2}, http.StatusUnprocessableEntity},
+		{"within-both", Budget{MaxSeries: 1}, Budget{MaxPoints: 5}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outer, cancelOuter := WithBudget(testContext(db, nil), tt.outer)
+			defer cancelOuter()
+
+			inner, cancelInner := WithBudget(outer, tt.inner)
+			defer cancelInner()
+
+			expectStatus(t, tt.status, func() {
+				Query(inner).FindInRange([]UUID{a}, metric, r)
+			})
+
+			want := Usage{Points: 3, Series: 1}
+			if got := UsageOf(outer); got != want {
+				t.Errorf("outer usage = %+v, want %+v", got, want)
+			}
+
+			if got := UsageOf(inner); got != want {
+				t.Errorf("inner usage = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckpoint_Loops(t *testing.T) {
+	db := newTestDB()
+	a, metric := NewUUID(), NewUUID()
+	db.put(a, metric, Points{{X: 1, Y: 1}})
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context)
+	}{
+		{
+			name: "group-for-each",
+			run: func(ctx context.Context) {
+				Group{{}, {}}.ForEachContext(ctx, func(pts Points) Points {
+					t.Errorf("unexpected call after cancellation")
+					return pts
+				})
+			},
+		},
+		{
+			name: "series-pivot",
+			run: func(ctx context.Context) {
+				SeriesGroup{{BucketID: a, MetricID: metric}}.Pivot(ctx, FillNull)
+			},
+		},
+		{
+			name: "rolling",
+			run: func(ctx context.Context) {
+				Points{{X: 1, Y: 1}}.RollingContext(ctx, AvgY, CountWindow(3, WindowRight))
+			},
+		},
+		{
+			name: "outliers",
+			run: func(ctx context.Context) {
+				Points{{X: 1, Y: 1}}.OutliersContext(ctx, ZScore(3))
+			},
+		},
+		{
+			name: "forecast",
+			run: func(ctx context.Context) {
+				Points{{X: 1, Y: 1}}.ForecastContext(ctx, SeasonalNaive(SeasonDay), 600, SeasonDay)
+			},
+		},
+		{
+			name: "stream",
+			run: func(ctx context.Context) {
+				OpenStream(ctx).WritePoints(Points{{X: 1, Y: 2}})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := WithBudget(testContext(db, nil), Budget{})
+			cancel()
+
+			expectStatus(t, http.StatusServiceUnavailable, func() {
+				tt.run(ctx)
+			})
+		})
+	}
+}
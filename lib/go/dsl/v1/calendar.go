@@ -0,0 +1,386 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// A FixedHoliday occurs every year at the same date, like the new year's day.
+type FixedHoliday struct {
+	Name  string     `json:"name"`
+	Month time.Month `json:"month"`
+	Day   int        `json:"day"`
+}
+
+// An EasterHoliday occurs every year at a date relative to the western (gregorian) Easter Sunday, like
+// Good Friday (-2) or Whit Monday (+50).
+type EasterHoliday struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+}
+
+// A Calendar defines the holidays and weekend days of a region or company. All dates are evaluated in the
+// location of the calculation, which should usually be the time zone of the bucket and not of the customer.
+//
+// Besides the predefined regions returned by RegionCalendar, custom company calendars can be loaded using
+// LoadCalendar, e.g. to add closing days to the holidays of a region:
+//  {
+//    "name": "ACME Bavaria",
+//    "extends": "DE-BY",
+//    "dates": ["2022-12-24", "2022-12-31"]
+//  }
+type Calendar struct {
+	// Name is an arbitrary name for displaying.
+	Name string `json:"name"`
+
+	// Extends optionally refers to a region as returned by RegionCalendar, whose holidays are also applied.
+	Extends string `json:"extends,omitempty"`
+
+	// Weekend contains the days which are never business days. If empty, Saturday and Sunday are used.
+	Weekend []time.Weekday `json:"weekend,omitempty"`
+
+	// Fixed contains the holidays which occur every year at the same date.
+	Fixed []FixedHoliday `json:"fixed,omitempty"`
+
+	// Easter contains the holidays which occur every year relative to Easter Sunday.
+	Easter []EasterHoliday `json:"easter,omitempty"`
+
+	// Dates contains additional one-off holidays or closing days.
+	Dates []Date `json:"dates,omitempty"`
+}
+
+// LoadCalendar parses a Calendar from the given json reader and validates it. Unknown fields are rejected,
+// just like Request does.
+func LoadCalendar(r io.Reader) (Calendar, error) {
+	var c Calendar
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return Calendar{}, fmt.Errorf("cannot decode calendar: %w", err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return Calendar{}, err
+	}
+
+	return c, nil
+}
+
+// Validate checks that the referenced region exists and that all dates are valid.
+func (c Calendar) Validate() error {
+	if c.Extends != "" {
+		if _, ok := regionCalendars[c.Extends]; !ok {
+			return fmt.Errorf("calendar '%s' extends unknown region '%s'", c.Name, c.Extends)
+		}
+	}
+
+	for _, day := range c.Weekend {
+		if day < time.Sunday || day > time.Saturday {
+			return fmt.Errorf("calendar '%s' has invalid weekend day %d", c.Name, day)
+		}
+	}
+
+	for _, h := range c.Fixed {
+		if h.Month < time.January || h.Month > time.December || h.Day < 1 || h.Day > maxDaysIn(h.Month) {
+			return fmt.Errorf("calendar '%s' has invalid holiday '%s': %d.%d", c.Name, h.Name, h.Day, h.Month)
+		}
+	}
+
+	for _, date := range c.Dates {
+		if _, err := time.Parse(dateFormat, string(date)); err != nil {
+			return fmt.Errorf("calendar '%s' has invalid date '%s': %w", c.Name, date, err)
+		}
+	}
+
+	return nil
+}
+
+// maxDaysIn returns the maximum amount of days of the month in any year, which is 29 for February.
+func maxDaysIn(month time.Month) int {
+	// 2000 is a leap year
+	return time.Date(2000, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// IsHoliday returns true, if the date of the given instant within its location is a holiday. Weekends are not
+// holidays, see also IsBusinessDay.
+func (c Calendar) IsHoliday(t time.Time) bool {
+	date := DateOf(t)
+	for _, d := range c.Dates {
+		if d == date {
+			return true
+		}
+	}
+
+	y, m, d := t.Date()
+	for _, h := range c.Fixed {
+		if h.Month == m && h.Day == d {
+			return true
+		}
+	}
+
+	if len(c.Easter) > 0 {
+		easter := EasterSunday(y)
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		offset := int(day.Sub(easter).Hours() / 24)
+		for _, h := range c.Easter {
+			if h.Offset == offset {
+				return true
+			}
+		}
+	}
+
+	if parent, ok := regionCalendars[c.Extends]; ok {
+		return parent.IsHoliday(t)
+	}
+
+	return false
+}
+
+// IsWeekend returns true, if the weekday of the given instant within its location is a weekend day.
+func (c Calendar) IsWeekend(t time.Time) bool {
+	if len(c.Weekend) == 0 {
+		return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+	}
+
+	for _, day := range c.Weekend {
+		if t.Weekday() == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsBusinessDay returns true, if the given instant is neither a weekend day nor a holiday.
+func (c Calendar) IsBusinessDay(t time.Time) bool {
+	return !c.IsWeekend(t) && !c.IsHoliday(t)
+}
+
+// Holidays returns all holidays of the given year in ascending order.
+func (c Calendar) Holidays(year int) []Date {
+	var res []Date
+	for day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC); day.Year() == year; day = day.AddDate(0, 0, 1) {
+		if c.IsHoliday(day) {
+			res = append(res, DateOf(day))
+		}
+	}
+
+	return res
+}
+
+// EasterSunday returns the date of the western (gregorian) Easter Sunday of the given year at midnight in UTC,
+// using the anonymous gregorian algorithm (Meeus/Jones/Butcher).
+func EasterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// RegionCalendar returns the predefined public holidays of the given region, identified by its ISO 3166 code,
+// like DE, DE-BY or AT. Returns false if the region is unknown. Regional holidays which only apply to
+// individual communities are not included.
+func RegionCalendar(region string) (Calendar, bool) {
+	c, ok := regionCalendars[region]
+	return c, ok
+}
+
+var regionCalendars = map[string]Calendar{
+	"DE": {
+		Name: "Deutschland",
+		Fixed: []FixedHoliday{
+			{Name: "Neujahr", Month: time.January, Day: 1},
+			{Name: "Tag der Arbeit", Month: time.May, Day: 1},
+			{Name: "Tag der Deutschen Einheit", Month: time.October, Day: 3},
+			{Name: "1. Weihnachtstag", Month: time.December, Day: 25},
+			{Name: "2. Weihnachtstag", Month: time.December, Day: 26},
+		},
+		Easter: []EasterHoliday{
+			{Name: "Karfreitag", Offset: -2},
+			{Name: "Ostermontag", Offset: 1},
+			{Name: "Christi Himmelfahrt", Offset: 39},
+			{Name: "Pfingstmontag", Offset: 50},
+		},
+	},
+	"DE-BW": {
+		Name:    "Baden-Württemberg",
+		Extends: "DE",
+		Fixed: []FixedHoliday{
+			{Name: "Heilige Drei Könige", Month: time.January, Day: 6},
+			{Name: "Allerheiligen", Month: time.November, Day: 1},
+		},
+		Easter: []EasterHoliday{{Name: "Fronleichnam", Offset: 60}},
+	},
+	"DE-BY": {
+		Name:    "Bayern",
+		Extends: "DE",
+		Fixed: []FixedHoliday{
+			{Name: "Heilige Drei Könige", Month: time.January, Day: 6},
+			{Name: "Allerheiligen", Month: time.November, Day: 1},
+		},
+		Easter: []EasterHoliday{{Name: "Fronleichnam", Offset: 60}},
+	},
+	"DE-NI": {
+		Name:    "Niedersachsen",
+		Extends: "DE",
+		Fixed:   []FixedHoliday{{Name: "Reformationstag", Month: time.October, Day: 31}},
+	},
+	"DE-NW": {
+		Name:    "Nordrhein-Westfalen",
+		Extends: "DE",
+		Fixed:   []FixedHoliday{{Name: "Allerheiligen", Month: time.November, Day: 1}},
+		Easter:  []EasterHoliday{{Name: "Fronleichnam", Offset: 60}},
+	},
+	"DE-SH": {
+		Name:    "Schleswig-Holstein",
+		Extends: "DE",
+		Fixed:   []FixedHoliday{{Name: "Reformationstag", Month: time.October, Day: 31}},
+	},
+	"AT": {
+		Name: "Österreich",
+		Fixed: []FixedHoliday{
+			{Name: "Neujahr", Month: time.January, Day: 1},
+			{Name: "Heilige Drei Könige", Month: time.January, Day: 6},
+			{Name: "Staatsfeiertag", Month: time.May, Day: 1},
+			{Name: "Mariä Himmelfahrt", Month: time.August, Day: 15},
+			{Name: "Nationalfeiertag", Month: time.October, Day: 26},
+			{Name: "Allerheiligen", Month: time.November, Day: 1},
+			{Name: "Mariä Empfängnis", Month: time.December, Day: 8},
+			{Name: "Christtag", Month: time.December, Day: 25},
+			{Name: "Stefanitag", Month: time.December, Day: 26},
+		},
+		Easter: []EasterHoliday{
+			{Name: "Ostermontag", Offset: 1},
+			{Name: "Christi Himmelfahrt", Offset: 39},
+			{Name: "Pfingstmontag", Offset: 50},
+			{Name: "Fronleichnam", Offset: 60},
+		},
+	},
+}
+
+// IsBusinessDay returns true, if the day of the given unix timestamp in seconds is a business day of the calendar
+// within the time zone.
+func (t Times) IsBusinessDay(c Calendar, x int64) bool {
+	return c.IsBusinessDay(time.Unix(x, 0).In(t.tz))
+}
+
+// NextBusinessDay returns the first UTC value in the given time zone and the according last UTC value of the first
+// business day after the day of the given unix timestamp in seconds. Panics, if there is no business day within
+// a year, which indicates a broken calendar.
+func (t Times) NextBusinessDay(c Calendar, x int64) Interval {
+	y, m, d := time.Unix(x, 0).In(t.tz).Date()
+	for i := 1; i <= 366; i++ {
+		start := time.Date(y, m, d+i, 0, 0, 0, 0, t.tz)
+		if c.IsBusinessDay(start) {
+			end := start.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			return Interval{
+				Min: start.Unix(),
+				Max: end.Unix(),
+			}
+		}
+	}
+
+	panic(fmt.Errorf("calendar '%s' has no business day within a year", c.Name))
+}
+
+// BusinessDays returns only those points, whose X value interpreted as a unix timestamp in seconds is located at
+// a business day of the calendar in the given location, e.g. to exclude holidays from consumption benchmarks.
+// The original points are not modified.
+func (p Points) BusinessDays(c Calendar, location *time.Location) Points {
+	business, _ := p.SplitBusinessDays(c, location)
+	return business
+}
+
+// SplitBusinessDays partitions the points into those located at a business day and all other points, which are
+// located at weekends or holidays of the calendar in the given location. The order of the points is kept.
+func (p Points) SplitBusinessDays(c Calendar, location *time.Location) (business, other Points) {
+	return p.splitDays(c.IsBusinessDay, location)
+}
+
+// SplitHolidays partitions the points into those located at a normal day, which includes weekends, and those located
+// at a holiday of the calendar in the given location, e.g. to compare the consumption of holidays against
+// normal days. The order of the points is kept.
+func (p Points) SplitHolidays(c Calendar, location *time.Location) (normal, holidays Points) {
+	holidays, normal = p.splitDays(c.IsHoliday, location)
+	return normal, holidays
+}
+
+// splitDays partitions the points by the result of the given day predicate.
+func (p Points) splitDays(pred func(t time.Time) bool, location *time.Location) (matching, other Points) {
+	matching = Points{}
+	other = Points{}
+
+	// points are usually sorted, so cache the result of the current day
+	var dayStart, dayEnd int64
+	matches := false
+	for _, point := range p {
+		if point.X < dayStart || point.X >= dayEnd {
+			t := time.Unix(point.X, 0).In(location)
+			y, m, d := t.Date()
+			start := time.Date(y, m, d, 0, 0, 0, 0, location)
+			dayStart = start.Unix()
+			dayEnd = start.AddDate(0, 0, 1).Unix()
+			matches = pred(t)
+		}
+
+		if matches {
+			matching = append(matching, point)
+		} else {
+			other = append(other, point)
+		}
+	}
+
+	return matching, other
+}
+
+// BusinessDays applies Points.BusinessDays on each series of the group.
+func (p Group) BusinessDays(c Calendar, location *time.Location) Group {
+	return p.ForEach(func(pts Points) Points {
+		return pts.BusinessDays(c, location)
+	})
+}
+
+// SplitBusinessDays applies Points.SplitBusinessDays on each series of the group. Both returned groups contain
+// a series for each series of the group in the same order, even if it is empty.
+func (p Group) SplitBusinessDays(c Calendar, location *time.Location) (business, other Group) {
+	business = make(Group, 0, len(p))
+	other = make(Group, 0, len(p))
+	for _, pts := range p {
+		b, o := pts.SplitBusinessDays(c, location)
+		business = append(business, b)
+		other = append(other, o)
+	}
+
+	return business, other
+}
+
+// SplitHolidays applies Points.SplitHolidays on each series of the group. Both returned groups contain a series
+// for each series of the group in the same order, even if it is empty.
+func (p Group) SplitHolidays(c Calendar, location *time.Location) (normal, holidays Group) {
+	normal = make(Group, 0, len(p))
+	holidays = make(Group, 0, len(p))
+	for _, pts := range p {
+		n, h := pts.SplitHolidays(c, location)
+		normal = append(normal, n)
+		holidays = append(holidays, h)
+	}
+
+	return normal, holidays
+}
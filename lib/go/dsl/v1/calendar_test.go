@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEasterSunday(t *testing.T) {
+	tests := map[int]string{
+		2019: "2019-04-21",
+		2022: "2022-04-17",
+		2024: "2024-03-31",
+		2038: "2038-04-25",
+	}
+
+	for year, want := range tests {
+		if got := DateOf(EasterSunday(year)); string(got) != want {
+			t.Errorf("EasterSunday(%d) = %v, want %v", year, got, want)
+		}
+	}
+}
+
+func TestCalendar_Holidays(t *testing.T) {
+	by, ok := RegionCalendar("DE-BY")
+	if !ok {
+		t.Fatal("missing DE-BY")
+	}
+
+	got := by.Holidays(2022)
+	want := []Date{
+		"2022-01-01", "2022-01-06", "2022-04-15", "2022-04-18", "2022-05-01", "2022-05-26", "2022-06-06",
+		"2022-06-16", "2022-10-03", "2022-11-01", "2022-12-25", "2022-12-26",
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Holidays() = %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Holidays() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLoadCalendar(t *testing.T) {
+	c, err := LoadCalendar(strings.NewReader(`{"name": "acme", "extends": "DE", "dates": ["2022-12-23"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	friday := time.Date(2022, 12, 23, 12, 0, 0, 0, berlin)
+	if c.IsBusinessDay(friday) {
+		t.Error("company closing day must not be a business day")
+	}
+
+	if !c.IsHoliday(time.Date(2022, 10, 3, 12, 0, 0, 0, berlin)) {
+		t.Error("extended region holiday is missing")
+	}
+
+	next := Times{tz: berlin}.NextBusinessDay(c, friday.Unix())
+	if want := time.Date(2022, 12, 27, 0, 0, 0, 0, berlin).Unix(); next.Min != want {
+		t.Errorf("NextBusinessDay() = %v, want %v", time.Unix(next.Min, 0), time.Unix(want, 0))
+	}
+
+	if _, err := LoadCalendar(strings.NewReader(`{"extends": "XX"}`)); err == nil {
+		t.Error("expected error for unknown region")
+	}
+
+	if _, err := LoadCalendar(strings.NewReader(`{"unknown": true}`)); err == nil {
+		t.Error("expected error for unknown field")
+	}
+
+	for _, fixed := range []string{`{"month": 2, "day": 30}`, `{"month": 4, "day": 31}`, `{"month": 13, "day": 1}`} {
+		if _, err := LoadCalendar(strings.NewReader(`{"fixed": [` + fixed + `]}`)); err == nil {
+			t.Errorf("expected error for impossible date %s", fixed)
+		}
+	}
+
+	if _, err := LoadCalendar(strings.NewReader(`{"fixed": [{"month": 2, "day": 29}]}`)); err != nil {
+		t.Errorf("expected leap day to be valid: %v", err)
+	}
+}
+
+func TestPoints_SplitBusinessDays(t *testing.T) {
+	de, _ := RegionCalendar("DE")
+	day := int64(24 * 3600)
+	monday := time.Date(2022, 10, 3, 0, 0, 0, 0, time.UTC).Unix() // Tag der Deutschen Einheit
+	p := Points{
+		{X: monday, Y: 1},
+		{X: monday + day, Y: 2},
+		{X: monday + 5*day, Y: 3},
+	}
+
+	business, other := p.SplitBusinessDays(de, time.UTC)
+	if len(business) != 1 || business[0] != p[1] || len(other) != 2 {
+		t.Errorf("SplitBusinessDays() = %v, %v", business, other)
+	}
+
+	normal, holidays := p.SplitHolidays(de, time.UTC)
+	if len(holidays) != 1 || holidays[0] != p[0] || len(normal) != 2 {
+		t.Errorf("SplitHolidays() = %v, %v", normal, holidays)
+	}
+}
+
+func TestGroup_SplitBusinessDays(t *testing.T) {
+	de, _ := RegionCalendar("DE")
+	day := int64(24 * 3600)
+	monday := time.Date(2022, 10, 3, 0, 0, 0, 0, time.UTC).Unix() // Tag der Deutschen Einheit
+	g := Group{
+		{{X: monday, Y: 1}, {X: monday + day, Y: 2}},
+		{{X: monday + 5*day, Y: 3}},
+	}
+
+	business, other := g.SplitBusinessDays(de, time.UTC)
+	if len(business) != 2 || len(business[0]) != 1 || len(business[1]) != 0 || len(other[0]) != 1 || len(other[1]) != 1 {
+		t.Errorf("SplitBusinessDays() = %v, %v", business, other)
+	}
+
+	normal, holidays := g.SplitHolidays(de, time.UTC)
+	if len(normal) != 2 || len(holidays[0]) != 1 || len(holidays[1]) != 0 || len(normal[0]) != 1 || len(normal[1]) != 1 {
+		t.Errorf("SplitHolidays() = %v, %v", normal, holidays)
+	}
+}
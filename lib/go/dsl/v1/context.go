@@ -17,14 +17,18 @@ type ctxKey string
 
 const (
 	ctxDB                 ctxKey = "mistral-db"
+	ctxBudget             ctxKey = "mistral-budget"
 	ctxHttpRequest        ctxKey = "http.Request"
 	ctxHttpResponseWriter ctxKey = "http.ResponseWriter"
 )
 
 const (
-	contentType  = "Content-Type"
-	mimeTypeXML  = "application/xml"
-	mimeTypeJSON = "application/json"
+	contentType        = "Content-Type"
+	contentDisposition = "Content-Disposition"
+	mimeTypeXML        = "application/xml"
+	mimeTypeJSON       = "application/json"
+	mimeTypeCSV        = "text/csv"
+	mimeTypeNDJSON     = "application/x-ndjson"
 )
 
 type httpError struct {
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+// CorrelationMethod is an enum like type to identify a correlation coefficient for Points.Correlate.
+type CorrelationMethod int
+
+// Valid determines if CorrelationMethod defines a valid enum.
+// See also Pearson and Spearman.
+func (m CorrelationMethod) Valid() bool {
+	return m >= Pearson && m <= Spearman
+}
+
+const (
+	// Pearson measures the linear correlation of the values.
+	Pearson CorrelationMethod = iota + 1
+
+	// Spearman measures the monotonic correlation, which is the Pearson correlation of the ranks of the values.
+	// Ties get the average rank. This is more robust against outliers and non-linear relations, like the cubic
+	// relation between wind speed and production.
+	Spearman
+)
+
+// LinearFit describes the least-squares regression line y = Slope * x + Intercept between the Y values of two
+// series, where x is taken from the left and y from the right series. All values are in the pre-scaled unit of
+// the according series, see also Unscale.
+type LinearFit struct {
+	Slope     float64 `json:"slope"`
+	Intercept float64 `json:"intercept"`
+
+	// R2 is the coefficient of determination, between 0 and 1.
+	R2 float64 `json:"r2"`
+
+	// N is the amount of aligned pairs which have been used.
+	N int64 `json:"n"`
+}
+
+// Unscale converts the slope and intercept into the unscaled units of both series. R2 and N are not affected.
+func (f LinearFit) Unscale(xScale, yScale int64) LinearFit {
+	f.Slope = f.Slope * float64(xScale) / float64(yScale)
+	f.Intercept = f.Intercept / float64(yScale)
+
+	return f
+}
+
+// ScatterPoints is a slice of Point elements, where X is a Y value of the left series and Y is the Y value of the
+// right series at the same timestamp. So in contrast to Points, X is usually not a time.
+type ScatterPoints []Point
+
+// Unscale divides X by xScale and Y by yScale using floating point arithmetics. This should be the last step.
+func (s ScatterPoints) Unscale(xScale, yScale int64) FScatterPoints {
+	res := make(FScatterPoints, 0, len(s))
+	for _, point := range s {
+		res = append(res, FScatterPoint{
+			X: float64(point.X) / float64(xScale),
+			Y: float64(point.Y) / float64(yScale),
+		})
+	}
+
+	return res
+}
+
+// FScatterPoint is the chart-ready representation of a scatter point. In contrast to FPoint, X is not a time but
+// an un-pre-multiplied value.
+type FScatterPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// FScatterPoints is just a slice of FScatterPoint elements.
+type FScatterPoints []FScatterPoint
+
+// Correlate aligns this series with the other series by X using InnerJoin semantics and returns the according
+// correlation coefficient of the Y values between -1 and 1. Returns false, if there are less than two aligned pairs
+// or if a series is constant. Both series must be sorted ascending by X, otherwise the result is undefined.
+func (p Points) Correlate(other Points, m CorrelationMethod) (float64, bool) {
+	return Math.Correlate(p, other, m)
+}
+
+// Covariance aligns this series with the other series like Correlate and returns the sample covariance of the Y
+// values in the product of both pre-scaled units. Returns false, if there are less than two aligned pairs.
+func (p Points) Covariance(other Points) (float64, bool) {
+	return Math.Covariance(p, other)
+}
+
+// LinearFit aligns this series with the other series like Correlate and returns the least-squares regression line,
+// which predicts the Y value of the other series from the Y value of this series. This is useful, to compare a
+// device against a reference device. Returns false, if there are less than two aligned pairs or if this series is
+// constant.
+func (p Points) LinearFit(other Points) (LinearFit, bool) {
+	return Math.LinearFit(p, other)
+}
+
+// Scatter aligns this series with the other series like Correlate and returns the pairs of Y values for a scatter
+// chart, sorted ascending by X. The pairs are thinned out to a grid of width x width cells, so that at most one
+// point per cell is kept. Width should be the amount of pixel of the chart, see also ViewportWidth.
+func (p Points) Scatter(other Points, width int64) ScatterPoints {
+	return Math.Scatter(p, other, width)
+}
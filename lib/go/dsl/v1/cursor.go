@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+// DefaultCursorWindow is the window length in seconds, which is used if CursorOptions.Window is not set.
+// For a 10 minute resolution, this results in 4320 points per chunk.
+const DefaultCursorWindow = 30 * SeasonDay
+
+// CursorOptions define how a Cursor splits the requested series into chunks.
+type CursorOptions struct {
+	// Window is the length of the time window of a chunk in seconds. Defaults to DefaultCursorWindow. It bounds the
+	// memory of a cursor, because only the current chunk is kept in memory.
+	Window int64
+}
+
+// A Chunk is the part of a Series within a single time window.
+type Chunk struct {
+	Series
+
+	// Interval is the inclusive time window of this chunk. The points are always within it but may not cover it.
+	Interval Interval
+}
+
+// A Cursor iterates over the chunks of multiple series, so that only a bounded amount of points must be held in
+// memory, in contrast to DB.FindInRange. The chunks are returned per bucket in the requested order and for each
+// bucket in ascending order of their time windows. Empty windows are skipped. A cursor can be closed early, e.g.
+// if a kernel has found what it was looking for. Example:
+//  cursor := db.Cursor(devices, metric, year, CursorOptions{})
+//  defer cursor.Close()
+//  for cursor.Next() {
+//    v, _ := cursor.Chunk().Points.Reduce(SumY)
+//    sum += v
+//  }
+type Cursor interface {
+	// Next loads the next chunk and returns false, if there are no more chunks or if the cursor has been closed.
+	// Panics for any other failure, just like the DB does.
+	Next() bool
+
+	// Chunk returns the current chunk, as loaded by Next. The points are only valid until the next call to Next.
+	Chunk() Chunk
+
+	// Close releases any resources. Subsequent calls to Next return false. It is safe to call Close multiple times.
+	Close()
+}
+
+// WindowCursor returns a reference Cursor, which loads each window of each bucket using DB.FindSeriesInRange.
+// The windows are aligned to r.Min but only cover the data range of each bucket, as returned by DB.MinMax, so that
+// buckets without data are skipped and an open interval does not iterate over empty windows. It never loads ahead
+// and is intended for DB implementations which have no better native way to iterate.
+func WindowCursor(db DB, bucketIDs []UUID, metricID UUID, r Interval, opts CursorOptions) Cursor {
+	if opts.Window <= 0 {
+		opts.Window = DefaultCursorWindow
+	}
+
+	if r.Min > r.Max {
+		bucketIDs = nil
+	}
+
+	return &windowCursor{
+		db:        db,
+		bucketIDs: bucketIDs,
+		metricID:  metricID,
+		r:         r,
+		window:    opts.Window,
+		bucket:    -1,
+	}
+}
+
+type windowCursor struct {
+	db        DB
+	bucketIDs []UUID
+	metricID  UUID
+	r         Interval
+	window    int64
+	bucket    int
+	next      int64 // start of the next window of the current bucket
+	last      int64 // the last timestamp of the current bucket, which must be loaded
+	exhausted bool  // true, if the last window of the current bucket has been loaded
+	chunk     Chunk
+	closed    bool
+}
+
+func (c *windowCursor) Next() bool {
+	for !c.closed {
+		if c.bucket < 0 || c.exhausted {
+			c.bucket++
+			if c.bucket >= len(c.bucketIDs) {
+				c.Close()
+				break
+			}
+
+			if !c.seek() {
+				c.exhausted = true
+				continue
+			}
+		}
+
+		w := Interval{Min: c.next, Max: c.r.Max}
+		if end := c.next + c.window - 1; end >= c.next && end < c.r.Max { // protect against overflow
+			w.Max = end
+		}
+
+		c.next = w.Max + 1
+		c.exhausted = w.Max >= c.last
+
+		g := c.db.FindSeriesInRange(c.bucketIDs[c.bucket:c.bucket+1], c.metricID, w)
+		if len(g) == 0 || len(g[0].Points) == 0 {
+			continue
+		}
+
+		c.chunk = Chunk{Series: g[0], Interval: w}
+		return true
+	}
+
+	c.chunk = Chunk{}
+	return false
+}
+
+// seek positions the cursor at the window of the current bucket, which contains its first point within the
+// requested interval, and returns false, if the bucket has no data within it.
+func (c *windowCursor) seek() bool {
+	dr := c.db.MinMax(c.bucketIDs[c.bucket], c.metricID)
+	if !dr.Valid || dr.MaxX < c.r.Min || dr.MinX > c.r.Max {
+		return false
+	}
+
+	c.next = c.r.Min
+	if dr.MinX > c.r.Min {
+		// unsigned arithmetic, because the distance may exceed math.MaxInt64
+		skip := uint64(dr.MinX-c.r.Min) / uint64(c.window) * uint64(c.window)
+		c.next = int64(uint64(c.r.Min) + skip)
+	}
+
+	c.last = c.r.Max
+	if dr.MaxX < c.r.Max {
+		c.last = dr.MaxX
+	}
+
+	c.exhausted = false
+	return true
+}
+
+func (c *windowCursor) Chunk() Chunk {
+	return c.chunk
+}
+
+func (c *windowCursor) Close() {
+	c.closed = true
+	c.chunk = Chunk{}
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWindowCursor(t *testing.T) {
+	db := newTestDB()
+	a, b, empty, metric := NewUUID(), NewUUID(), NewUUID(), NewUUID()
+	db.put(a, metric, Points{{X: 0, Y: 1}, {X: 5, Y: 2}, {X: 25, Y: 3}})
+	db.put(b, metric, Points{{X: 12, Y: 4}, {X: math.MaxInt64 - 1, Y: 5}})
+
+	type chunk struct {
+		bucket UUID
+		r      Interval
+		pts    int
+	}
+
+	tests := []struct {
+		name string
+		r    Interval
+		opts CursorOptions
+		want []chunk
+	}{
+		{
+			name: "windows",
+			r:    Interval{Min: 0, Max: 29},
+			opts: CursorOptions{Window: 10},
+			want: []chunk{
+				{a, Interval{Min: 0, Max: 9}, 2},
+				{a, Interval{Min: 20, Max: 29}, 1},
+				{b, Interval{Min: 10, Max: 19}, 1},
+			},
+		},
+		{
+			name: "clipped",
+			r:    Interval{Min: 5, Max: 26},
+			opts: CursorOptions{Window: 20},
+			want: []chunk{
+				{a, Interval{Min: 5, Max: 24}, 1},
+				{a, Interval{Min: 25, Max: 26}, 1},
+				{b, Interval{Min: 5, Max: 24}, 1},
+			},
+		},
+		{
+			name: "overflow",
+			r:    Interval{Min: math.MaxInt64 - 14, Max: math.MaxInt64},
+			opts: CursorOptions{Window: 10},
+			want: []chunk{
+				{b, Interval{Min: math.MaxInt64 - 4, Max: math.MaxInt64}, 1},
+			},
+		},
+		{
+			name: "empty",
+			r:    Interval{Min: 10, Max: 0},
+			opts: CursorOptions{Window: 10},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := WindowCursor(db, []UUID{a, empty, b}, metric, tt.r, tt.opts)
+			defer c.Close()
+
+			var got []chunk
+			for c.Next() {
+				got = append(got, chunk{c.Chunk().BucketID, c.Chunk().Interval, len(c.Chunk().Points)})
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowCursor_Open(t *testing.T) {
+	db := newTestDB()
+	a, outside, metric := NewUUID(), NewUUID(), NewUUID()
+	db.put(a, metric, Points{{X: 0, Y: 1}, {X: 5, Y: 2}, {X: 25, Y: 3}})
+	db.put(outside, metric, Points{{X: math.MinInt64, Y: 4}})
+
+	c := WindowCursor(db, []UUID{a, outside}, metric, Interval{Min: math.MinInt64 + 1, Max: math.MaxInt64}, CursorOptions{Window: 10})
+	defer c.Close()
+
+	var got []Interval
+	for c.Next() {
+		if c.Chunk().BucketID != a {
+			t.Fatalf("unexpected bucket %v", c.Chunk().BucketID)
+		}
+
+		got = append(got, c.Chunk().Interval)
+	}
+
+	want := []Interval{{Min: -7, Max: 2}, {Min: 3, Max: 12}, {Min: 23, Max: 32}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWindowCursor_Close(t *testing.T) {
+	db := newTestDB()
+	a, metric := NewUUID(), NewUUID()
+	db.put(a, metric, Points{{X: 0, Y: 1}, {X: 10, Y: 2}})
+
+	c := WindowCursor(db, []UUID{a}, metric, Interval{Min: 0, Max: 19}, CursorOptions{Window: 10})
+	if !c.Next() {
+		t.Fatalf("expected a first chunk")
+	}
+
+	c.Close()
+	c.Close()
+
+	if c.Next() {
+		t.Fatalf("expected no chunk after close")
+	}
+
+	if len(c.Chunk().Points) != 0 {
+		t.Errorf("expected empty chunk after close")
+	}
+}
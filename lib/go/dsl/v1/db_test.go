@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"sort"
+)
+
+// testDB is a minimal in-memory DB for tests.
+type testDB struct {
+	buckets map[UUID]Bucket
+	groups  map[UUID]BucketGroup
+	metrics map[UUID]Metric
+	descs   map[UUID]Descriptor
+	series  map[UUID]map[UUID]Points // bucket => metric => points
+}
+
+func newTestDB() *testDB {
+	return &testDB{
+		buckets: map[UUID]Bucket{},
+		groups:  map[UUID]BucketGroup{},
+		metrics: map[UUID]Metric{},
+		descs:   map[UUID]Descriptor{},
+		series:  map[UUID]map[UUID]Points{},
+	}
+}
+
+func (db *testDB) put(bucketID, metricID UUID, p Points) {
+	if db.series[bucketID] == nil {
+		db.series[bucketID] = map[UUID]Points{}
+	}
+
+	db.series[bucketID][metricID] = p
+}
+
+func (db *testDB) Bucket(id UUID) (Bucket, bool) {
+	b, ok := db.buckets[id]
+	return b, ok
+}
+
+func (db *testDB) BucketGroup(id UUID) (BucketGroup, bool) {
+	g, ok := db.groups[id]
+	return g, ok
+}
+
+func (db *testDB) BucketGroups(groupType BucketGroupType) []BucketGroup {
+	var res []BucketGroup
+	for _, g := range db.groups {
+		if groupType == "" || g.Type == groupType {
+			res = append(res, g)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(res[i].ID[:], res[j].ID[:]) < 0
+	})
+
+	return res
+}
+
+func (db *testDB) FindBuckets(q BucketQuery) []Bucket {
+	var res []Bucket
+	for _, b := range db.buckets {
+		if !q.MatchXAttr(b.XAttr) || !db.isMember(b.ID, q) {
+			continue
+		}
+
+		res = append(res, b)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(res[i].ID[:], res[j].ID[:]) < 0
+	})
+
+	return res
+}
+
+func (db *testDB) isMember(bucketID UUID, q BucketQuery) bool {
+	if q.GroupType == "" && len(q.GroupIDs) == 0 {
+		return true
+	}
+
+	for _, g := range db.groups {
+		if q.GroupType != "" && g.Type != q.GroupType {
+			continue
+		}
+
+		if len(q.GroupIDs) > 0 && !containsUUID(q.GroupIDs, g.ID) {
+			continue
+		}
+
+		if containsUUID(g.Buckets, bucketID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsUUID(ids []UUID, id UUID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (db *testDB) Metric(id UUID) (Metric, bool) {
+	m, ok := db.metrics[id]
+	return m, ok
+}
+
+func (db *testDB) Descriptor(metricID UUID) (Descriptor, bool) {
+	d, ok := db.descs[metricID]
+	return d, ok
+}
+
+func (db *testDB) ScaleOf(metricID UUID) int64 {
+	if m, ok := db.metrics[metricID]; ok && m.Scale > 0 {
+		return m.Scale
+	}
+
+	return 1
+}
+
+func (db *testDB) FindRanges(bucketIDs []UUID) []DataRange {
+	return nil
+}
+
+func (db *testDB) MinMax(bucketID, metricID UUID) DataRange {
+	p := db.series[bucketID][metricID]
+	if len(p) == 0 {
+		return DataRange{ID: metricID}
+	}
+
+	return DataRange{ID: metricID, MinX: p[0].X, MaxX: p[len(p)-1].X, Valid: true}
+}
+
+func (db *testDB) load(bucketID, metricID UUID, r Interval) (Points, bool) {
+	p, ok := db.series[bucketID][metricID]
+	if !ok {
+		return nil, false
+	}
+
+	res := Points{}
+	for _, point := range p {
+		if point.X >= r.Min && point.X <= r.Max {
+			res = append(res, point)
+		}
+	}
+
+	return res, true
+}
+
+func (db *testDB) FindInRange(bucketIDs []UUID, metricID UUID, r Interval) Group {
+	var g Group
+	for _, id := range bucketIDs {
+		if p, ok := db.load(id, metricID, r); ok {
+			g = append(g, p)
+		}
+	}
+
+	return g
+}
+
+func (db *testDB) FindSeriesInRange(bucketIDs []UUID, metricID UUID, r Interval) SeriesGroup {
+	var g SeriesGroup
+	for _, id := range bucketIDs {
+		p, _ := db.load(id, metricID, r)
+		if p == nil {
+			p = Points{}
+		}
+
+		g = append(g, Series{BucketID: id, MetricID: metricID, Scale: db.ScaleOf(metricID), Points: p})
+	}
+
+	return g
+}
+
+func (db *testDB) FindAllInRange(bucketIDs []UUID, metricIDs []UUID, r Interval) SeriesGroup {
+	var g SeriesGroup
+	for _, id := range bucketIDs {
+		for _, metricID := range metricIDs {
+			g = append(g, db.FindSeriesInRange([]UUID{id}, metricID, r)...)
+		}
+	}
+
+	return g
+}
+
+func (db *testDB) Execute(plan Plan) SeriesGroup {
+	return nil // no pushdown
+}
+
+func (db *testDB) Cursor(bucketIDs []UUID, metricID UUID, r Interval, opts CursorOptions) Cursor {
+	return WindowCursor(db, bucketIDs, metricID, r, opts)
+}
+
+// testContext returns a context containing the given db and a GET request with the given headers.
+func testContext(db DB, header map[string]string) context.Context {
+	r := httptest.NewRequest("GET", "/", nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+
+	ctx := WithHttpRequest(r.Context(), r)
+	ctx = WithHttpResponse(ctx, httptest.NewRecorder())
+
+	return WithDB(ctx, db)
+}
@@ -0,0 +1,318 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrDivisionByZero is returned by Decimal.Div if the divisor is zero.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// A Decimal is a fixed-point number which carries its scale, so that the represented value is Value / Scale.
+// This is the same representation as used by a pre-scaled Point.Y value together with ScaleOf. In contrast to
+// a plain int64, a Decimal cannot be accidentally combined with a value of another scale, because all operations
+// rescale explicitly and detect overflows. See also ScaledPoints.
+//
+// Just like FPoint states, floating point numbers should only be used for display purposes and not for calculations.
+// So use Float64 or String only as the last step.
+type Decimal struct {
+	Value int64 `json:"value"`
+	Scale int64 `json:"scale"`
+}
+
+// NewDecimal creates a Decimal from a pre-scaled value and its scale. A scale of zero or less is treated as 1.
+func NewDecimal(value, scale int64) Decimal {
+	return Decimal{Value: value, Scale: normScale(scale)}
+}
+
+// Rescale converts the value into the given scale. If the new scale is smaller, the value is rounded using the given
+// mode. Returns an error wrapping ErrOverflow, if the result does not fit into an int64.
+func (d Decimal) Rescale(scale int64, mode RoundingMode) (Decimal, error) {
+	scale = normScale(scale)
+	v, err := rescale(d.Value, normScale(d.Scale), scale, mode)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	return Decimal{Value: v, Scale: scale}, nil
+}
+
+// Add returns d + o in the larger scale of both. The mode is only applied, if a scale is not a multiple of the other.
+func (d Decimal) Add(o Decimal, mode RoundingMode) (Decimal, error) {
+	a, b, err := d.common(o, mode)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	v, ok := addInt64(a.Value, b.Value)
+	if !ok {
+		return Decimal{}, fmt.Errorf("cannot add %v and %v: %w", d, o, ErrOverflow)
+	}
+
+	return Decimal{Value: v, Scale: a.Scale}, nil
+}
+
+// Sub returns d - o in the larger scale of both. The mode is only applied, if a scale is not a multiple of the other.
+func (d Decimal) Sub(o Decimal, mode RoundingMode) (Decimal, error) {
+	a, b, err := d.common(o, mode)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	v, ok := subInt64(a.Value, b.Value)
+	if !ok {
+		return Decimal{}, fmt.Errorf("cannot subtract %v and %v: %w", d, o, ErrOverflow)
+	}
+
+	return Decimal{Value: v, Scale: a.Scale}, nil
+}
+
+// Mul returns d * o in the larger scale of both, rounded using the given mode. Intermediate results are
+// calculated without overflow, so only the final result must fit into an int64.
+func (d Decimal) Mul(o Decimal, mode RoundingMode) (Decimal, error) {
+	scale := maxScale(d.Scale, o.Scale)
+
+	// d.Value/d.Scale * o.Value/o.Scale * scale
+	num := new(big.Int).Mul(big.NewInt(d.Value), big.NewInt(o.Value))
+	num.Mul(num, big.NewInt(scale))
+	den := new(big.Int).Mul(big.NewInt(normScale(d.Scale)), big.NewInt(normScale(o.Scale)))
+
+	v, ok := quoRound(num, den, mode)
+	if !ok {
+		return Decimal{}, fmt.Errorf("cannot multiply %v and %v: %w", d, o, ErrOverflow)
+	}
+
+	return Decimal{Value: v, Scale: scale}, nil
+}
+
+// Div returns d / o in the larger scale of both, rounded using the given mode. Intermediate results are
+// calculated without overflow, so only the final result must fit into an int64. Returns ErrDivisionByZero if
+// o is zero.
+func (d Decimal) Div(o Decimal, mode RoundingMode) (Decimal, error) {
+	if o.Value == 0 {
+		return Decimal{}, fmt.Errorf("cannot divide %v by %v: %w", d, o, ErrDivisionByZero)
+	}
+
+	scale := maxScale(d.Scale, o.Scale)
+
+	// (d.Value/d.Scale) / (o.Value/o.Scale) * scale
+	num := new(big.Int).Mul(big.NewInt(d.Value), big.NewInt(normScale(o.Scale)))
+	num.Mul(num, big.NewInt(scale))
+	den := new(big.Int).Mul(big.NewInt(normScale(d.Scale)), big.NewInt(o.Value))
+
+	v, ok := quoRound(num, den, mode)
+	if !ok {
+		return Decimal{}, fmt.Errorf("cannot divide %v by %v: %w", d, o, ErrOverflow)
+	}
+
+	return Decimal{Value: v, Scale: scale}, nil
+}
+
+// Cmp compares both values independent of their scale and returns -1 if d < o, 0 if d == o and +1 if d > o.
+func (d Decimal) Cmp(o Decimal) int {
+	a := new(big.Int).Mul(big.NewInt(d.Value), big.NewInt(normScale(o.Scale)))
+	b := new(big.Int).Mul(big.NewInt(o.Value), big.NewInt(normScale(d.Scale)))
+
+	return a.Cmp(b)
+}
+
+// Float64 returns the floating point representation, which should only be used for displaying.
+func (d Decimal) Float64() float64 {
+	return float64(d.Value) / float64(normScale(d.Scale))
+}
+
+// String returns the exact decimal representation, e.g. 12.345 for a value of 12345 and a scale of 1000.
+// If the scale is not a power of 10, the float representation is returned.
+func (d Decimal) String() string {
+	scale := normScale(d.Scale)
+	digits := 0
+	for s := scale; s > 1; s /= 10 {
+		if s%10 != 0 {
+			return strconv.FormatFloat(d.Float64(), 'f', -1, 64)
+		}
+
+		digits++
+	}
+
+	str := strconv.FormatInt(d.Value, 10)
+	if digits == 0 {
+		return str
+	}
+
+	sign := ""
+	if strings.HasPrefix(str, "-") {
+		sign = "-"
+		str = str[1:]
+	}
+
+	if len(str) <= digits {
+		str = strings.Repeat("0", digits-len(str)+1) + str
+	}
+
+	return sign + str[:len(str)-digits] + "." + str[len(str)-digits:]
+}
+
+// common rescales both values into the larger scale.
+func (d Decimal) common(o Decimal, mode RoundingMode) (Decimal, Decimal, error) {
+	scale := maxScale(d.Scale, o.Scale)
+	a, err := d.Rescale(scale, mode)
+	if err != nil {
+		return Decimal{}, Decimal{}, err
+	}
+
+	b, err := o.Rescale(scale, mode)
+	if err != nil {
+		return Decimal{}, Decimal{}, err
+	}
+
+	return a, b, nil
+}
+
+// ScaledPoints is a series of pre-scaled Points which carries its scale, so that the meaning of each Y value is
+// Y / Scale, just like a Decimal. Use ScaledOf to create an instance for a metric.
+type ScaledPoints struct {
+	Points Points `json:"points"`
+	Scale  int64  `json:"scale"`
+}
+
+// ScaledOf attaches the scale of the given metric, as returned by ScaleOf, to the points.
+func ScaledOf(db DB, metricID UUID, p Points) ScaledPoints {
+	return ScaledPoints{Points: p, Scale: normScale(db.ScaleOf(metricID))}
+}
+
+// At returns the Y value at the given index as a Decimal. Panics if the index is out of range.
+func (s ScaledPoints) At(i int) Decimal {
+	return NewDecimal(s.Points[i].Y, s.Scale)
+}
+
+// Rescale returns a new series with all Y values converted into the given scale. If the new scale is smaller,
+// the values are rounded using the given mode. Returns an error wrapping ErrOverflow, if a value does not fit into
+// an int64. The original points are not modified.
+func (s ScaledPoints) Rescale(scale int64, mode RoundingMode) (ScaledPoints, error) {
+	scale = normScale(scale)
+	from := normScale(s.Scale)
+	res := make(Points, 0, len(s.Points))
+	for _, point := range s.Points {
+		y, err := rescale(point.Y, from, scale, mode)
+		if err != nil {
+			return ScaledPoints{}, fmt.Errorf("cannot rescale point at x=%d: %w", point.X, err)
+		}
+
+		res = append(res, Point{X: point.X, Y: y})
+	}
+
+	return ScaledPoints{Points: res, Scale: scale}, nil
+}
+
+// Unscale converts the series into FPoints using its own scale. See also Points.Unscale.
+func (s ScaledPoints) Unscale() FPoints {
+	return s.Points.Unscale(normScale(s.Scale))
+}
+
+// normScale treats any scale of zero or less as 1.
+func normScale(scale int64) int64 {
+	if scale <= 0 {
+		return 1
+	}
+
+	return scale
+}
+
+func maxScale(a, b int64) int64 {
+	a, b = normScale(a), normScale(b)
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// rescale converts v from one scale into another. Usually, scales are a power of 10 and therefore the fast paths
+// apply.
+func rescale(v, from, to int64, mode RoundingMode) (int64, error) {
+	switch {
+	case from == to:
+		return v, nil
+	case to%from == 0:
+		r, ok := mulInt64(v, to/from)
+		if !ok {
+			return 0, fmt.Errorf("cannot rescale %d from %d to %d: %w", v, from, to, ErrOverflow)
+		}
+
+		return r, nil
+	case from%to == 0:
+		r, _ := mode.Div(v, from/to) // cannot fail, because from/to > 1
+		return r, nil
+	default:
+		num := new(big.Int).Mul(big.NewInt(v), big.NewInt(to))
+		r, ok := quoRound(num, big.NewInt(from), mode)
+		if !ok {
+			return 0, fmt.Errorf("cannot rescale %d from %d to %d: %w", v, from, to, ErrOverflow)
+		}
+
+		return r, nil
+	}
+}
+
+// quoRound returns num / den rounded according to the mode or false, if the result does not fit into an int64.
+func quoRound(num, den *big.Int, mode RoundingMode) (int64, bool) {
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() != 0 {
+		negative := num.Sign() != den.Sign()
+		twiceRem := new(big.Int).Abs(rem)
+		twiceRem.Lsh(twiceRem, 1)
+		half := twiceRem.Cmp(new(big.Int).Abs(den))
+
+		if mode.awayFromZero(negative, half, q.Bit(0) != 0) {
+			if negative {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+
+	if !q.IsInt64() {
+		return 0, false
+	}
+
+	return q.Int64(), true
+}
+
+func addInt64(a, b int64) (int64, bool) {
+	r := a + b
+	if (b > 0 && r < a) || (b < 0 && r > a) {
+		return 0, false
+	}
+
+	return r, true
+}
+
+func subInt64(a, b int64) (int64, bool) {
+	r := a - b
+	if (b > 0 && r > a) || (b < 0 && r < a) {
+		return 0, false
+	}
+
+	return r, true
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	r := a * b
+	if r/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+
+	return r, true
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDecimal_Rescale(t *testing.T) {
+	tests := []struct {
+		name    string
+		d       Decimal
+		scale   int64
+		mode    RoundingMode
+		want    Decimal
+		wantErr error
+	}{
+		{"up", NewDecimal(1234, 10), 1000, RoundHalfUp, NewDecimal(123400, 1000), nil},
+		{"down-half-up", NewDecimal(1235, 1000), 100, RoundHalfUp, NewDecimal(124, 100), nil},
+		{"down-half-even", NewDecimal(1225, 1000), 100, RoundHalfEven, NewDecimal(122, 100), nil},
+		{"down-floor-neg", NewDecimal(-1231, 1000), 10, RoundFloor, NewDecimal(-13, 10), nil},
+		{"odd-scale", NewDecimal(10, 3), 10, RoundHalfUp, NewDecimal(33, 10), nil},
+		{"zero-scale", NewDecimal(5, 0), 10, RoundHalfUp, NewDecimal(50, 10), nil},
+		{"overflow", NewDecimal(math.MaxInt64/10, 1), 1000, RoundHalfUp, Decimal{}, ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.d.Rescale(tt.scale, tt.mode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rescale() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Rescale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimal_Arithmetics(t *testing.T) {
+	a := NewDecimal(15, 10)     // 1.5
+	b := NewDecimal(2250, 1000) // 2.25
+
+	if got, err := a.Add(b, RoundHalfUp); err != nil || got != NewDecimal(3750, 1000) {
+		t.Errorf("Add() = %v, %v", got, err)
+	}
+
+	if got, err := a.Sub(b, RoundHalfUp); err != nil || got != NewDecimal(-750, 1000) {
+		t.Errorf("Sub() = %v, %v", got, err)
+	}
+
+	if got, err := a.Mul(b, RoundHalfUp); err != nil || got != NewDecimal(3375, 1000) {
+		t.Errorf("Mul() = %v, %v", got, err)
+	}
+
+	if got, err := a.Div(b, RoundHalfUp); err != nil || got != NewDecimal(667, 1000) {
+		t.Errorf("Div() = %v, %v", got, err)
+	}
+
+	if _, err := a.Div(NewDecimal(0, 10), RoundHalfUp); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("Div() by zero = %v", err)
+	}
+
+	if _, err := NewDecimal(math.MaxInt64, 1).Add(NewDecimal(1, 1), RoundHalfUp); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add() overflow = %v", err)
+	}
+
+	// the intermediate product overflows, but the result fits
+	big := NewDecimal(math.MaxInt64/2, 1000)
+	if got, err := big.Mul(NewDecimal(2000, 1000), RoundHalfUp); err != nil || got.Value != math.MaxInt64-1 {
+		t.Errorf("Mul() = %v, %v", got, err)
+	}
+
+	if c := NewDecimal(10, 10).Cmp(NewDecimal(1000, 1000)); c != 0 {
+		t.Errorf("Cmp() = %v", c)
+	}
+}
+
+func TestDecimal_String(t *testing.T) {
+	tests := []struct {
+		d    Decimal
+		want string
+	}{
+		{NewDecimal(12345, 1000), "12.345"},
+		{NewDecimal(-5, 100), "-0.05"},
+		{NewDecimal(42, 1), "42"},
+		{NewDecimal(1, 4), "0.25"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("String() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestScaledPoints_Rescale(t *testing.T) {
+	s := ScaledPoints{Points: Points{{X: 1, Y: 1234}, {X: 2, Y: -1235}}, Scale: 1000}
+	got, err := s.Rescale(10, RoundHalfUp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Points{{X: 1, Y: 12}, {X: 2, Y: -12}}
+	if got.Scale != 10 || len(got.Points) != len(want) || got.Points[0] != want[0] || got.Points[1] != want[1] {
+		t.Fatalf("Rescale() = %v", got)
+	}
+
+	if s.Points[0].Y != 1234 {
+		t.Fatal("original points have been modified")
+	}
+}
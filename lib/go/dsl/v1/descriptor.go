@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+// A Descriptor defines the nature of a time series, just like the Descriptor of the REST API. In contrast to
+// Metric, it contains the full model about how the keys and values have been measured, so that a kernel can decide
+// how to aggregate correctly instead of hardcoding assumptions about each metric. See also DB.Descriptor.
+type Descriptor struct {
+	ID       UUID         `json:"id"`
+	Key      KeySpec      `json:"key"`
+	Value    ValueSpec    `json:"value"`
+	Sampling SamplingSpec `json:"sampling"`
+	XAttr    XAttr        `json:"xattr"`
+}
+
+// KeyType is the type of the keys of a time series. Currently, only KeyTimestamp is specified.
+type KeyType string
+
+// KeyTimestamp denotes keys which are unix timestamps.
+const KeyTimestamp KeyType = "timestamp"
+
+// KeyUnit is the unit of the keys of a time series. Currently, only KeySeconds is specified.
+type KeyUnit string
+
+// KeySeconds denotes keys in seconds, as expected by all build-in functions.
+const KeySeconds KeyUnit = "seconds"
+
+// KeySpec describes the keys (X values) of a time series.
+type KeySpec struct {
+	Type KeyType `json:"type"`
+	Unit KeyUnit `json:"unit"`
+}
+
+// ValueSpec describes the values (Y values) of a time series.
+type ValueSpec struct {
+	Unit        Unit        `json:"unit"`
+	Aggregation Aggregation `json:"aggregation"`
+	Scale       int64       `json:"scale"`
+}
+
+// SamplingType describes how the combination of a key and value has been measured.
+type SamplingType string
+
+const (
+	// PeriodStart denotes a value aggregated over a period, whose key is the beginning of the period.
+	PeriodStart SamplingType = "periodStart"
+
+	// PeriodEnd denotes a value aggregated over a period, whose key is the end of the period.
+	PeriodEnd SamplingType = "periodEnd"
+
+	// Instant denotes a value which has been captured without any relevant duration at exactly its key.
+	Instant SamplingType = "instant"
+
+	// LevelBegin denotes a state which is valid since (inclusive) its key until the next key.
+	LevelBegin SamplingType = "levelBegin"
+
+	// LevelEnd denotes a state which is valid until (inclusive) its key since the preceding key.
+	LevelEnd SamplingType = "levelEnd"
+)
+
+// Period describes the base interval of a sampling. Implementations must accept non-standardized periods,
+// so a kernel must expect other values than the declared constants.
+type Period string
+
+const (
+	// Period10m denotes a constant interval of 600 seconds.
+	Period10m Period = "10m"
+
+	// Period15m denotes a constant interval of 900 seconds.
+	Period15m Period = "15m"
+
+	// PeriodDaily denotes a time zone specific daily interval.
+	//
+	// Deprecated: time zone specific aggregations cannot be processed correctly in another time zone.
+	PeriodDaily Period = "daily"
+
+	// PeriodMonthly denotes a time zone specific monthly interval.
+	//
+	// Deprecated: time zone specific aggregations cannot be processed correctly in another time zone.
+	PeriodMonthly Period = "monthly"
+
+	// PeriodNone denotes a value without any significant duration, which is always the case for Instant,
+	// LevelBegin and LevelEnd.
+	PeriodNone Period = "none"
+)
+
+// Seconds returns the constant length of the period in seconds. PeriodNone has a length of 0. Returns false
+// for time zone specific or unknown periods.
+func (p Period) Seconds() (int64, bool) {
+	switch p {
+	case Period10m:
+		return 600, true
+	case Period15m:
+		return 900, true
+	case PeriodNone:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// SamplingSpec describes how and over which period the values of a time series have been measured.
+type SamplingSpec struct {
+	Type   SamplingType `json:"type"`
+	Period Period       `json:"period"`
+}
+
+// Drift returns the drift in seconds, which moves the key of a value into the period it has been measured in, as
+// expected by the GroupBy* functions. For PeriodEnd, this is the negative period, so that e.g. the value at
+// 00:00:00 is accounted to the previous day. For any other type or unknown periods NoDrift is returned.
+// Example:
+//  desc, _ := db.Descriptor(metric)
+//  pts.GroupByDay(desc.Sampling.Drift(), AlignGroupStart, loc)
+func (s SamplingSpec) Drift() int64 {
+	if s.Type != PeriodEnd {
+		return NoDrift
+	}
+
+	seconds, ok := s.Period.Seconds()
+	if !ok {
+		return NoDrift
+	}
+
+	return -seconds
+}
+
+// XAttr is an arbitrary map of attributes, which are attached by a middleware to improve data interoperability.
+type XAttr map[string]interface{}
+
+// String returns the value of the given key, if it exists and is a string.
+func (x XAttr) String(key string) (string, bool) {
+	s, ok := x[key].(string)
+	return s, ok
+}
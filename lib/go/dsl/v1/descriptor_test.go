@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSamplingSpec_Drift(t *testing.T) {
+	tests := []struct {
+		name string
+		spec SamplingSpec
+		want int64
+	}{
+		{"period-end-10m", SamplingSpec{Type: PeriodEnd, Period: Period10m}, -600},
+		{"period-end-15m", SamplingSpec{Type: PeriodEnd, Period: Period15m}, -900},
+		{"period-start", SamplingSpec{Type: PeriodStart, Period: Period15m}, NoDrift},
+		{"instant", SamplingSpec{Type: Instant, Period: PeriodNone}, NoDrift},
+		{"period-end-daily", SamplingSpec{Type: PeriodEnd, Period: PeriodDaily}, NoDrift},
+		{"period-end-custom", SamplingSpec{Type: PeriodEnd, Period: "5m"}, NoDrift},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.spec.Drift(); got != tt.want {
+				t.Errorf("Drift() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescriptor_UnmarshalJSON(t *testing.T) {
+	buf := []byte(`{
+		"id": "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+		"key": {"type": "timestamp", "unit": "seconds"},
+		"value": {"unit": "kWh", "aggregation": "sum", "scale": 1000},
+		"sampling": {"type": "periodEnd", "period": "10m"},
+		"xattr": {"source": "scada", "channel": 4}
+	}`)
+
+	var d Descriptor
+	if err := json.Unmarshal(buf, &d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.ID.String() != "3fa85f64-5717-4562-b3fc-2c963f66afa6" {
+		t.Errorf("unexpected id %v", d.ID)
+	}
+
+	if d.Key != (KeySpec{Type: KeyTimestamp, Unit: KeySeconds}) {
+		t.Errorf("unexpected key spec %v", d.Key)
+	}
+
+	if d.Value != (ValueSpec{Unit: KilowattHour, Aggregation: AggregationSum, Scale: 1000}) {
+		t.Errorf("unexpected value spec %v", d.Value)
+	}
+
+	if d.Sampling.Drift() != -600 {
+		t.Errorf("unexpected drift %v", d.Sampling.Drift())
+	}
+
+	if s, ok := d.XAttr.String("source"); !ok || s != "scada" {
+		t.Errorf("unexpected xattr source %v", s)
+	}
+
+	if _, ok := d.XAttr.String("channel"); ok {
+		t.Errorf("expected non-string xattr to be rejected")
+	}
+}
@@ -8,8 +8,8 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"mime"
 	"net/http"
-	"reflect"
 	"strconv"
 	"time"
 
@@ -74,14 +74,19 @@ func (t TZ) Parse() (*time.Location, error) {
 	return loc, nil
 }
 
-// Query unpacks the context specific DB.
+// Query unpacks the context specific DB. Each query respects the cancellation and deadline of the given context
+// and the Budget, if any, and panics accordingly. See also Checkpoint.
 func Query(ctx context.Context) DB {
 	db := ctx.Value(ctxDB).(DB)
 	if db == nil {
 		panic("context does not contain a DB")
 	}
 
-	return db
+	if g, ok := db.(guardedDB); ok {
+		db = g.db
+	}
+
+	return guardedDB{ctx: ctx, db: db}
 }
 
 // DB describes the contract to the Mistral database and provides a bunch of query methods.
@@ -90,10 +95,24 @@ type DB interface {
 	// time series data. Returns false if no such bucket exist. Panics for any other failure.
 	Bucket(id UUID) (Bucket, bool)
 
+	// BucketGroup loads the metadata and the members of a group of buckets. Returns false if no such group exist.
+	// Panics for any other failure.
+	BucketGroup(id UUID) (BucketGroup, bool)
+
+	// BucketGroups returns all groups of the given type or all groups, if the type is empty.
+	BucketGroups(groupType BucketGroupType) []BucketGroup
+
+	// FindBuckets returns all buckets matching the given query, sorted by id.
+	FindBuckets(q BucketQuery) []Bucket
+
 	// Metric loads the metric metadata and describes a specific time series data which is required to interpret
 	// the meaning of x and y values. Returns false if no such metric exist. Panics for any other failure.
 	Metric(id UUID) (Metric, bool)
 
+	// Descriptor loads the full descriptor of the metric, including its key, value and sampling specification.
+	// Returns false if no such descriptor exist. Panics for any other failure.
+	Descriptor(metricID UUID) (Descriptor, bool)
+
 	// ScaleOf returns the scale for the given metric ID or returns 1 if not found. A multiple of 10,
 	// usually in the range of 1, 10, 100 or 1000.
 	ScaleOf(metricID UUID) int64
@@ -109,6 +128,26 @@ type DB interface {
 
 	// FindInRange loads those (time) series of the given buckets identified by the metric id, which exists.
 	FindInRange(bucketIDs []UUID, metricID UUID, r Interval) Group
+
+	// FindSeriesInRange is like FindInRange but returns exactly one labeled Series per given bucket in the same
+	// order, even if a bucket has no data. In that case, the Points of the Series are empty.
+	FindSeriesInRange(bucketIDs []UUID, metricID UUID, r Interval) SeriesGroup
+
+	// FindAllInRange is like FindSeriesInRange but loads multiple metrics in a single call. It returns exactly one
+	// labeled Series per given bucket and metric, ordered by bucket first and then by metric as given. Use the
+	// SeriesGroup helpers like Metric or Align, to select and align the series.
+	FindAllInRange(bucketIDs []UUID, metricIDs []UUID, r Interval) SeriesGroup
+
+	// Execute runs the given Plan as a whole, so that an implementation can push the grouping, reduction and
+	// downscaling down to its storage. Implementations without such a pushdown must return nil (not an empty
+	// SeriesGroup), so that the DB returned by Query evaluates the plan using Plan.Eval and accounts the raw
+	// points like any other query. Panics, if the plan is not valid.
+	Execute(plan Plan) SeriesGroup
+
+	// Cursor is like FindSeriesInRange but returns the series in chunks per bucket and time window, so that
+	// arbitrary long intervals and large portfolios can be processed with bounded memory. Implementations without
+	// a native iteration may just return a WindowCursor.
+	Cursor(bucketIDs []UUID, metricID UUID, r Interval, opts CursorOptions) Cursor
 }
 
 type translatedEntity interface {
@@ -134,6 +173,7 @@ func translateNames(ctx context.Context, ids []UUID, f func(UUID) (translatedEnt
 		info, exists := f(id)
 		if !exists {
 			names = append(names, id.String())
+			continue
 		}
 
 		name := translateName(ctx, info)
@@ -183,35 +223,71 @@ func Request(ctx context.Context, v interface{}) {
 	}
 }
 
-// Response marshals the given value as json. If the first field has a xml-tag, the response is treated as
-// xml. Panics for illegal arguments. Subsequent calls are undefined.
+// Response marshals the given value according to the Accept header of the request. The following media types
+// are supported:
+//  * application/json for any value
+//  * application/xml for any value
+//  * text/csv for tabular values implementing CSVEncoder, like Table
+//  * application/x-ndjson for point series implementing NDJSONEncoder, like Points or FPoints
+// The media types of the Accept header are evaluated in order of their quality. If the Accept header is missing,
+// only contains wildcards or if no media type is supported for the given value, the default is used. For
+// backwards compatibility, the default is xml if the first field of a struct has a xml-tag and json otherwise.
+// So falling back to json is explicit and never fails with a 406 Not Acceptable. Panics for illegal arguments.
+// Subsequent calls are undefined. See also Attachment.
 func Response(ctx context.Context, v interface{}) {
-	w := ctx.Value(ctxHttpResponseWriter).(http.ResponseWriter)
-	if w == nil {
+	w, ok := ctx.Value(ctxHttpResponseWriter).(http.ResponseWriter)
+	if !ok || w == nil {
 		panic("context does not contain a http.ResponseWriter")
 	}
 
-	if typ := reflect.TypeOf(v); typ.NumField() > 0 {
-		if _, isXML := typ.Field(0).Tag.Lookup("xml"); isXML {
-			w.Header().Set(contentType, mimeTypeXML)
-			enc := xml.NewEncoder(w)
-			if err := enc.Encode(v); err != nil {
-				panic(fmt.Errorf("cannot encode xml response: %w", err))
-			}
+	accept := ""
+	if req, ok := ctx.Value(ctxHttpRequest).(*http.Request); ok && req != nil {
+		accept = req.Header.Get("Accept")
+	}
 
-			if err := enc.Flush(); err != nil {
-				panic(fmt.Errorf("cannot flush xml response: %w", err))
-			}
+	w.Header().Add("Vary", "Accept")
 
-			return
+	switch negotiate(accept, v) {
+	case mimeTypeXML:
+		w.Header().Set(contentType, mimeTypeXML)
+		enc := xml.NewEncoder(w)
+		if err := enc.Encode(v); err != nil {
+			panic(fmt.Errorf("cannot encode xml response: %w", err))
+		}
+
+		if err := enc.Flush(); err != nil {
+			panic(fmt.Errorf("cannot flush xml response: %w", err))
+		}
+	case mimeTypeCSV:
+		w.Header().Set(contentType, mimeTypeCSV+"; charset=utf-8")
+		if err := v.(CSVEncoder).WriteCSV(w, Timezone(ctx)); err != nil {
+			panic(fmt.Errorf("cannot encode csv response: %w", err))
+		}
+	case mimeTypeNDJSON:
+		w.Header().Set(contentType, mimeTypeNDJSON)
+		if err := v.(NDJSONEncoder).WriteNDJSON(w); err != nil {
+			panic(fmt.Errorf("cannot encode ndjson response: %w", err))
+		}
+	default:
+		w.Header().Set(contentType, mimeTypeJSON)
+		enc := json.NewEncoder(w)
+		if err := enc.Encode(v); err != nil {
+			panic(fmt.Errorf("cannot encode json response: %w", err))
 		}
 	}
+}
 
-	w.Header().Set(contentType, mimeTypeJSON)
-	enc := json.NewEncoder(w)
-	if err := enc.Encode(v); err != nil {
-		panic(fmt.Errorf("cannot encode json response: %w", err))
+// Attachment is like Response but additionally sets the Content-Disposition header, so that browsers offer to
+// save the response using the given filename, e.g. report.csv. Non-ASCII filenames are encoded according to
+// RFC 2231.
+func Attachment(ctx context.Context, filename string, v interface{}) {
+	w, ok := ctx.Value(ctxHttpResponseWriter).(http.ResponseWriter)
+	if !ok || w == nil {
+		panic("context does not contain a http.ResponseWriter")
 	}
+
+	w.Header().Set(contentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	Response(ctx, v)
 }
 
 // MatchLanguage inspects the request (Accept-Language) and context and matches that against the given language
@@ -314,6 +390,23 @@ func (t Times) Year(year int) Interval {
 	}
 }
 
+// ThisMonth returns the UTC Interval in seconds of the current month based on the current Location.
+func (t Times) ThisMonth() Interval {
+	now := t.Now()
+	return t.Month(now.Year(), now.Month())
+}
+
+// Month returns the first UTC value in the given time zone for the given month and the according last UTC value.
+func (t Times) Month(year int, month time.Month) Interval {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, t.tz)
+	end := start.AddDate(0, 1, 0).Add(-time.Nanosecond)
+
+	return Interval{
+		Min: start.Unix(),
+		Max: end.Unix(),
+	}
+}
+
 // Today returns the first UTC value in the given time zone for the current day and the according last UTC value.
 func (t Times) Today() Interval {
 	return t.DayOf(0)
@@ -358,6 +451,8 @@ type Metric struct {
 	Description  string                 `json:"description"`
 	Scale        int64                  `json:"scale"`
 	Resolution   time.Duration          `json:"resolution"`
+	Unit         Unit                   `json:"unit"`
+	Aggregation  Aggregation            `json:"aggregation"`
 	Translations map[string]Translation `json:"translations"`
 }
 
@@ -393,6 +488,7 @@ type Bucket struct {
 	Name         string                 `json:"name"`
 	Description  string                 `json:"description"`
 	Timezone     string                 `json:"timezone"`
+	XAttr        XAttr                  `json:"xattr"`
 	Translations map[string]Translation `json:"translations"`
 }
 
@@ -570,7 +666,7 @@ func (p Points) First() (Point, bool) {
 // Downscale discards points which are insignificant when displaying in the given width.
 // This uses the default downscale implementation, which may change between revisions to
 // optimize experience. Width should be the amount of pixel on which a line chart should be drawn.
-// See also M4 which is currently used.
+// See also M4 which is currently used and DownscaleWith to choose another algorithm.
 func (p Points) Downscale(width int64) Points {
 	return p.M4(width)
 }
@@ -589,6 +685,81 @@ func (p Points) M4(width int64) Points {
 	return Math.M4(p, width)
 }
 
+// LTTB applies the Largest-Triangle-Three-Buckets downscaling algorithm by Sveinn Steinarsson.
+// See https://skemman.is/bitstream/1946/15343/3/SS_MSthesis.pdf. It expects that the given series is already sorted.
+//
+// In contrast to M4, exactly one point per bucket is selected, which is the point forming the largest triangle
+// with the selected point of the previous bucket and the average of the next bucket. The first and the last point
+// are always kept. The result contains at most width points and is sorted.
+//
+// If the width is larger than the amount of available points, the original points are returned.
+func (p Points) LTTB(width int64) Points {
+	return Math.LTTB(p, width)
+}
+
+// MinMax divides the interval of the series into width/2 buckets and selects the points with the minimum and
+// maximum Y value of each bucket in their original order. The first and the last point are always kept.
+// The result contains at most width+2 points and is sorted. It expects that the given series is already sorted.
+//
+// If the width is larger than the amount of available points, the original points are returned.
+func (p Points) MinMax(width int64) Points {
+	return Math.MinMax(p, width)
+}
+
+// AvgBuckets divides the interval of the series into width buckets and returns a single point per bucket, with
+// the X value of the first point of the bucket and the average of all Y values of the bucket using AvgY semantics.
+// The first and the last point are kept as is, so the result contains at most width+2 points and is sorted.
+// This looks smooth but hides peaks. It expects that the given series is already sorted.
+//
+// If the width is larger than the amount of available points, the original points are returned.
+func (p Points) AvgBuckets(width int64) Points {
+	return Math.AvgBuckets(p, width)
+}
+
+// DownscaleWith applies the given algorithm. This allows a kernel to let the client choose the algorithm, e.g.
+// from a request parameter. The width should be the amount of pixel as returned by ViewportWidth.
+// An invalid algorithm is treated like DownscaleDefault.
+func (p Points) DownscaleWith(algorithm DownscaleAlgorithm, width int64) Points {
+	switch algorithm {
+	case DownscaleM4:
+		return p.M4(width)
+	case DownscaleLTTB:
+		return p.LTTB(width)
+	case DownscaleMinMax:
+		return p.MinMax(width)
+	case DownscaleAvg:
+		return p.AvgBuckets(width)
+	default:
+		return p.Downscale(width)
+	}
+}
+
+// DownscaleAlgorithm is an enum like type to identify a downscaling algorithm for Points.DownscaleWith.
+type DownscaleAlgorithm int
+
+// Valid determines if DownscaleAlgorithm defines a valid enum.
+// See also DownscaleDefault, DownscaleM4, DownscaleLTTB, DownscaleMinMax and DownscaleAvg.
+func (a DownscaleAlgorithm) Valid() bool {
+	return a >= DownscaleDefault && a <= DownscaleAvg
+}
+
+const (
+	// DownscaleDefault uses whatever Points.Downscale uses.
+	DownscaleDefault DownscaleAlgorithm = iota + 1
+
+	// DownscaleM4 uses Points.M4.
+	DownscaleM4
+
+	// DownscaleLTTB uses Points.LTTB.
+	DownscaleLTTB
+
+	// DownscaleMinMax uses Points.MinMax.
+	DownscaleMinMax
+
+	// DownscaleAvg uses Points.AvgBuckets.
+	DownscaleAvg
+)
+
 // AggregateFunc is an enum like type to identify an aggregate function for Group.Reduce or Group.ReduceTransposed
 // functions.
 type AggregateFunc int
@@ -616,6 +787,32 @@ const (
 	Count
 )
 
+// XSelector is an enum like type to identify which X value is used for the reduced point of each group by
+// Group.ReduceX.
+type XSelector int
+
+// Valid determines if XSelector defines a valid enum.
+// See also XGroupStart, XGroupEnd, XExtreme and XGroupMid.
+func (s XSelector) Valid() bool {
+	return s >= XGroupStart && s <= XGroupMid
+}
+
+const (
+	// XGroupStart uses the first X of each group. This is the behavior of Group.Reduce.
+	XGroupStart XSelector = iota + 1
+
+	// XGroupEnd uses the last X of each group.
+	XGroupEnd
+
+	// XExtreme uses the X of the selected point, which is the argmin for MinY and the argmax for MaxY. If multiple
+	// points have the same extreme value, the first one wins. For all other aggregate functions, there is no
+	// selected point and XGroupStart applies.
+	XExtreme
+
+	// XGroupMid uses the truncated midpoint between the first and the last X of each group.
+	XGroupMid
+)
+
 // Group is slice of (time) series points.
 type Group []Points
 
@@ -637,10 +834,25 @@ func (p Group) First() Points {
 //    [(11|12), (13|14), (15|16)]
 // Note that the X value if always the first of each group.
 // Also note, that this is weired for Max, because it returns the "wrong" x (the first, as defined).
+// Use ReduceX to select another X, e.g. the timestamp of the actual maximum.
 func (p Group) Reduce(f AggregateFunc) Points {
 	return Math.GroupReduce(p, f)
 }
 
+// ReduceX is like Reduce but the X value of each reduced point is selected by x. Example using MaxY:
+//  [
+//    [(1|2), (2|7), (4|5)],
+//    [(5|6), (7|8), (9|1)],
+//  ]
+//  => XGroupStart: [(1|7), (5|8)]
+//  => XGroupEnd:   [(4|7), (9|8)]
+//  => XExtreme:    [(2|7), (7|8)]
+//  => XGroupMid:   [(2|7), (7|8)]
+// Groups which cannot be reduced (e.g. empty groups) are omitted, just like Reduce does.
+func (p Group) ReduceX(f AggregateFunc, x XSelector) Points {
+	return Math.GroupReduceX(p, f, x)
+}
+
 // ReduceTransposed applies an AggregateFunc on the group and returns a single series again.
 // OuterGroupByX transposes the points of each group into a new artificial group and invokes f on it. It requires that
 // each group is sorted ascending by X. The result on unsorted groups is undefined. Example:
@@ -661,7 +873,8 @@ func (p Group) ReduceTransposed(f AggregateFunc) Points {
 
 // ForEach allows an in-line modification of each point series inside Group. For example
 // one can SnapToGrid, then create a GroupByDay aggregation with a reduction into a series by using the AvgY operator.
-// A lot of operations can be applied in-place to reduce memory footprint and pressure. See also ForEachF.
+// A lot of operations can be applied in-place to reduce memory footprint and pressure. See also ForEachF and
+// ForEachContext.
 func (p Group) ForEach(f func(pts Points) Points) Group {
 	for i, points := range p {
 		p[i] = f(points)
@@ -670,6 +883,17 @@ func (p Group) ForEach(f func(pts Points) Points) Group {
 	return p
 }
 
+// ForEachContext is like ForEach but performs a Checkpoint before each series, so that a long-running
+// evaluation over a large group is aborted, if the context is done or its budget is exceeded.
+func (p Group) ForEachContext(ctx context.Context, f func(pts Points) Points) Group {
+	for i, points := range p {
+		Checkpoint(ctx)
+		p[i] = f(points)
+	}
+
+	return p
+}
+
 // ForEachF is like ForEach but allows a transformation into a floating point series resulting in a floating point
 // group of series. It is guaranteed that the transformation performs additional heap allocations and therefore
 // should only be used after Downsampling.
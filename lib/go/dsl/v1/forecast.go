@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import "context"
+
+// SeasonDay is the length of a daily season in seconds, e.g. for the daily cycle of solar production.
+const SeasonDay = 24 * 3600
+
+// SeasonWeek is the length of a weekly season in seconds, e.g. for the weekly cycle of a consumption.
+const SeasonWeek = 7 * SeasonDay
+
+// ForecastMethod is an enum like type to identify a forecast algorithm. See also ForecastModel.
+type ForecastMethod int
+
+// Valid determines if ForecastMethod defines a valid enum.
+// See also ForecastSeasonalNaive, ForecastMovingAverage and ForecastHoltWinters.
+func (m ForecastMethod) Valid() bool {
+	return m >= ForecastSeasonalNaive && m <= ForecastHoltWinters
+}
+
+const (
+	// ForecastSeasonalNaive repeats the values of the last season, e.g. tomorrow will be like today.
+	ForecastSeasonalNaive ForecastMethod = iota + 1
+
+	// ForecastMovingAverage continues with the average of the last Window, as a constant.
+	ForecastMovingAverage
+
+	// ForecastHoltWinters applies the additive Holt-Winters (triple exponential smoothing) method, which models
+	// a level, a trend and a seasonal component.
+	ForecastHoltWinters
+)
+
+// ForecastModel configures a forecast. Use the according constructors SeasonalNaive, MovingAverage or HoltWinters
+// to create a valid configuration.
+type ForecastModel struct {
+	// Method defines the algorithm.
+	Method ForecastMethod
+
+	// Season is the length of a season in seconds, like SeasonDay or SeasonWeek.
+	Season int64
+
+	// Window defines the values to average for ForecastMovingAverage.
+	Window Window
+
+	// Alpha, Beta and Gamma are the smoothing factors between 0 and 1 for the level, trend and seasonal component of
+	// ForecastHoltWinters.
+	Alpha, Beta, Gamma float64
+
+	// Confidence is the level of the prediction interval between 0 and 1, like 0.95. A zero level disables the
+	// calculation of the interval.
+	Confidence float64
+}
+
+// SeasonalNaive returns a ForecastModel for ForecastSeasonalNaive.
+func SeasonalNaive(season int64) ForecastModel {
+	return ForecastModel{Method: ForecastSeasonalNaive, Season: season}
+}
+
+// MovingAverage returns a ForecastModel for ForecastMovingAverage. The alignment of the window is ignored, because
+// the window always ends at the last point.
+func MovingAverage(w Window) ForecastModel {
+	return ForecastModel{Method: ForecastMovingAverage, Window: w}
+}
+
+// HoltWinters returns a ForecastModel for ForecastHoltWinters. Typical smoothing factors are between 0.1 and 0.3.
+func HoltWinters(season int64, alpha, beta, gamma float64) ForecastModel {
+	return ForecastModel{Method: ForecastHoltWinters, Season: season, Alpha: alpha, Beta: beta, Gamma: gamma}
+}
+
+// WithConfidence returns a copy of the model, which also calculates a prediction interval of the given level,
+// like 0.95.
+func (m ForecastModel) WithConfidence(level float64) ForecastModel {
+	m.Confidence = level
+	return m
+}
+
+// A Forecast contains the predicted points and the optional prediction interval. All series share the same X values.
+type Forecast struct {
+	Points Points `json:"points"`
+
+	// Lower is the lower bound of the prediction interval or empty, if no confidence has been requested.
+	Lower Points `json:"lower"`
+
+	// Upper is the upper bound of the prediction interval or empty, if no confidence has been requested.
+	Upper Points `json:"upper"`
+}
+
+// Forecast predicts the continuation of the series using the given model. The forecast starts step seconds after
+// the last point and contains a point for each step up to and including the horizon in seconds after the last
+// point. Just like measured points, X is in seconds since Unix Epoch, so a forecast can be joined with the measured
+// data and grouped by GroupByDay. Example to complete the current month with 10 minute values:
+//  month := Time(ctx).ThisMonth()
+//  last, _ := pts.Last()
+//  fc := pts.Forecast(HoltWinters(SeasonDay, 0.2, 0.05, 0.2).WithConfidence(0.95), 600, month.Max-last.X)
+// At least two seasons of data are required by ForecastHoltWinters and one season by ForecastSeasonalNaive,
+// otherwise the forecast is empty. It expects that points are ordered ascended by X (==time) and are
+// equidistant. The result is undefined, if the dataset is not sorted correctly.
+func (p Points) Forecast(m ForecastModel, step, horizon int64) Forecast {
+	return Math.Forecast(p, m, step, horizon)
+}
+
+// ForecastContext is like Forecast but the fitting and the prediction perform a Checkpoint regularly, so that
+// a forecast based on a long history is aborted, as soon as the context is done or its budget is exceeded.
+func (p Points) ForecastContext(ctx context.Context, m ForecastModel, step, horizon int64) Forecast {
+	return Math.ForecastContext(ctx, p, m, step, horizon)
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+// BinMode is an enum like type which defines how the bins of a Histogram are determined.
+type BinMode int
+
+// Valid determines if BinMode defines a valid enum.
+// See also BinFixedWidth, BinEdges and BinQuantiles.
+func (m BinMode) Valid() bool {
+	return m >= BinFixedWidth && m <= BinQuantiles
+}
+
+const (
+	// BinFixedWidth creates bins of the same width, aligned at multiples of the width, so that e.g. a width of 100
+	// creates the bins [0, 100), [100, 200) and so on. Only bins between the smallest and largest Y are returned.
+	BinFixedWidth BinMode = iota + 1
+
+	// BinEdges uses the given ascending edges, so that n edges create n-1 bins. Values outside of the edges are
+	// not counted.
+	BinEdges
+
+	// BinQuantiles creates the given amount of bins, so that each bin contains roughly the same amount of values.
+	BinQuantiles
+)
+
+// Binning configures the bins of a Histogram. Use the according constructors FixedWidthBins, EdgeBins or
+// QuantileBins to create a valid configuration.
+type Binning struct {
+	Mode  BinMode
+	Width int64
+	Edges []int64
+	Count int
+}
+
+// FixedWidthBins returns a Binning for BinFixedWidth. The width is in the pre-scaled unit of the Y values.
+func FixedWidthBins(width int64) Binning {
+	return Binning{Mode: BinFixedWidth, Width: width}
+}
+
+// EdgeBins returns a Binning for BinEdges. The edges are in the pre-scaled unit of the Y values.
+func EdgeBins(edges ...int64) Binning {
+	return Binning{Mode: BinEdges, Edges: edges}
+}
+
+// QuantileBins returns a Binning for BinQuantiles.
+func QuantileBins(count int) Binning {
+	return Binning{Mode: BinQuantiles, Count: count}
+}
+
+// A Bin counts the values within [Min, Max). The last bin of a Histogram also includes Max.
+type Bin struct {
+	Min   int64 `json:"min"`
+	Max   int64 `json:"max"`
+	Count int64 `json:"count"`
+}
+
+// Histogram is a slice of Bin elements, sorted ascending and without overlaps.
+type Histogram []Bin
+
+// Total returns the sum of all counts.
+func (h Histogram) Total() int64 {
+	var sum int64
+	for _, bin := range h {
+		sum += bin.Count
+	}
+
+	return sum
+}
+
+// Unscale divides the bin boundaries by yScale using floating point arithmetics and calculates the share of each
+// bin. This should be the last step.
+func (h Histogram) Unscale(yScale int64) FHistogram {
+	total := float64(h.Total())
+	res := make(FHistogram, 0, len(h))
+	for _, bin := range h {
+		share := 0.0
+		if total > 0 {
+			share = float64(bin.Count) / total
+		}
+
+		res = append(res, FBin{
+			Min:   float64(bin.Min) / float64(yScale),
+			Max:   float64(bin.Max) / float64(yScale),
+			Count: bin.Count,
+			Share: share,
+		})
+	}
+
+	return res
+}
+
+// FBin is the chart-ready representation of a Bin. See also FPoint.
+type FBin struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int64   `json:"count"`
+
+	// Share is the relative amount of the count, between 0 and 1.
+	Share float64 `json:"share"`
+}
+
+// FHistogram is just a slice of FBin elements.
+type FHistogram []FBin
+
+// A DurationPoint is part of a DurationCurve and describes for how long the value Y has been reached or exceeded.
+type DurationPoint struct {
+	// Duration is usually in seconds.
+	Duration int64 `json:"duration"`
+
+	// Y is usually a pre-scaled decimal metric value.
+	Y int64 `json:"y"`
+}
+
+// DurationCurve is a slice of DurationPoint elements, sorted ascending by Duration and therefore descending by Y.
+type DurationCurve []DurationPoint
+
+// Unscale divides by yScale using floating point arithmetics and converts the duration into hours and into
+// a percentage of the total duration. This should be the last step.
+func (c DurationCurve) Unscale(yScale int64) FDurationCurve {
+	total := float64(0)
+	if len(c) > 0 {
+		total = float64(c[len(c)-1].Duration)
+	}
+
+	res := make(FDurationCurve, 0, len(c))
+	for _, point := range c {
+		percent := 0.0
+		if total > 0 {
+			percent = float64(point.Duration) / total * 100
+		}
+
+		res = append(res, FDurationPoint{
+			Hours:   float64(point.Duration) / 3600,
+			Percent: percent,
+			Y:       float64(point.Y) / float64(yScale),
+		})
+	}
+
+	return res
+}
+
+// FDurationPoint is the chart-ready representation of a DurationPoint. See also FPoint.
+type FDurationPoint struct {
+	Hours   float64 `json:"hours"`
+	Percent float64 `json:"percent"`
+	Y       float64 `json:"y"`
+}
+
+// FDurationCurve is just a slice of FDurationPoint elements.
+type FDurationCurve []FDurationPoint
+
+// Histogram counts the Y values of the series into the bins defined by the given Binning, e.g. the distribution
+// of wind speeds. The order of the points is irrelevant.
+func (p Points) Histogram(b Binning) Histogram {
+	return Math.Histogram(p, b)
+}
+
+// LoadDurationCurve sorts the Y values descending and accumulates the duration, so that each point tells for how
+// long the value has been reached or exceeded. Each point of the series represents the given period in seconds,
+// e.g. 600 for 10 minute values. Example with a period of 600:
+//  [(0|5), (600|9), (1200|7)]
+//  => [(600|9), (1200|7), (1800|5)]
+// The curve contains at most width points, which are selected evenly distributed over the duration and always
+// include the first and the last point. A width of 0 or less returns all points. See also ViewportWidth.
+func (p Points) LoadDurationCurve(period, width int64) DurationCurve {
+	return Math.LoadDurationCurve(p, period, width)
+}
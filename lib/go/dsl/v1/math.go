@@ -3,7 +3,10 @@
 
 package miel
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Math provides a polymorphic entry point (vtable) for a bunch of intrinsically optimized math implementations.
 var Math Intrinsics = mathStub{}
@@ -26,10 +29,66 @@ type Intrinsics interface {
 	PointsReduce(p Points, f AggregateFunc) (int64, bool)
 	// M4 is documented at Points.M4.
 	M4(p Points, width int64) Points
+	// LTTB is documented at Points.LTTB.
+	LTTB(p Points, width int64) Points
+	// MinMax is documented at Points.MinMax.
+	MinMax(p Points, width int64) Points
+	// AvgBuckets is documented at Points.AvgBuckets.
+	AvgBuckets(p Points, width int64) Points
 	// GroupReduce is documented at Group.Reduce.
 	GroupReduce(g Group, f AggregateFunc) Points
+	// GroupReduceX is documented at Group.ReduceX.
+	GroupReduceX(g Group, f AggregateFunc, x XSelector) Points
 	// GroupReduceTransposed is documented at Group.ReduceTransposed.
 	GroupReduceTransposed(g Group, f AggregateFunc) Points
+	// Combine is documented at Points.Combine.
+	Combine(a, b Points, op BinaryOp, opts ArithOptions) Points
+	// GroupCombine is documented at Group.Combine.
+	GroupCombine(g Group, op BinaryOp, opts ArithOptions) Points
+	// CumSum is documented at Points.CumSum.
+	CumSum(p Points) Points
+	// Delta is documented at Points.Delta.
+	Delta(p Points, c Counter) Points
+	// Rate is documented at Points.Rate.
+	Rate(p Points, c Counter, per int64) Points
+	// Derivative is documented at Points.Derivative.
+	Derivative(p Points, per int64) Points
+	// Rolling is documented at Points.Rolling.
+	Rolling(p Points, f AggregateFunc, w Window) Points
+	// RollingContext is documented at Points.RollingContext.
+	RollingContext(ctx context.Context, p Points, f AggregateFunc, w Window) Points
+	// Outliers is documented at Points.Outliers.
+	Outliers(p Points, d OutlierDetector) (clean, flagged Points)
+	// OutliersContext is documented at Points.OutliersContext.
+	OutliersContext(ctx context.Context, p Points, d OutlierDetector) (clean, flagged Points)
+	// Integrate is documented at Points.Integrate.
+	Integrate(p Points, period int64, rule IntegrationRule) Points
+	// Histogram is documented at Points.Histogram.
+	Histogram(p Points, b Binning) Histogram
+	// LoadDurationCurve is documented at Points.LoadDurationCurve.
+	LoadDurationCurve(p Points, period, width int64) DurationCurve
+	// Correlate is documented at Points.Correlate.
+	Correlate(a, b Points, m CorrelationMethod) (float64, bool)
+	// Covariance is documented at Points.Covariance.
+	Covariance(a, b Points) (float64, bool)
+	// LinearFit is documented at Points.LinearFit.
+	LinearFit(a, b Points) (LinearFit, bool)
+	// Scatter is documented at Points.Scatter.
+	Scatter(a, b Points, width int64) ScatterPoints
+	// Forecast is documented at Points.Forecast.
+	Forecast(p Points, m ForecastModel, step, horizon int64) Forecast
+	// ForecastContext is documented at Points.ForecastContext.
+	ForecastContext(ctx context.Context, p Points, m ForecastModel, step, horizon int64) Forecast
+	// Quality is documented at Points.Quality.
+	Quality(p Points, resolution int64, r Interval, location *time.Location) QualityReport
+	// Sort is documented at Points.Sort.
+	Sort(p Points) Points
+	// Dedupe is documented at Points.Dedupe.
+	Dedupe(p Points, keep DedupeMode) Points
+	// Merge is documented at Group.Merge.
+	Merge(g Group) Points
+	// Align is documented at Group.Align.
+	Align(g Group, join JoinMode, fill int64) Group
 }
 
 type mathStub struct {
@@ -43,6 +102,10 @@ func (m mathStub) GroupReduce(g Group, f AggregateFunc) Points {
 	return Points{}
 }
 
+func (m mathStub) GroupReduceX(g Group, f AggregateFunc, x XSelector) Points {
+	return Points{}
+}
+
 func (m mathStub) GroupReduceTransposed(g Group, f AggregateFunc) Points {
 	return Points{}
 }
@@ -75,6 +138,18 @@ func (m mathStub) M4(p Points, width int64) Points {
 	return Points{}
 }
 
+func (m mathStub) LTTB(p Points, width int64) Points {
+	return Points{}
+}
+
+func (m mathStub) MinMax(p Points, width int64) Points {
+	return Points{}
+}
+
+func (m mathStub) AvgBuckets(p Points, width int64) Points {
+	return Points{}
+}
+
 func (m mathStub) GroupByYear(p Points, drift int64, align bool, location *time.Location) Group {
 	return Group{}
 }
@@ -90,3 +165,102 @@ func (m mathStub) GroupByMonth(p Points, drift int64, align bool, location *time
 func (m mathStub) Scale(p Points, x, y int64) Points {
 	return Points{}
 }
+
+func (m mathStub) Combine(a, b Points, op BinaryOp, opts ArithOptions) Points {
+	return Points{}
+}
+
+func (m mathStub) GroupCombine(g Group, op BinaryOp, opts ArithOptions) Points {
+	return Points{}
+}
+
+func (m mathStub) CumSum(p Points) Points {
+	return Points{}
+}
+
+func (m mathStub) Delta(p Points, c Counter) Points {
+	return Points{}
+}
+
+func (m mathStub) Rate(p Points, c Counter, per int64) Points {
+	return Points{}
+}
+
+func (m mathStub) Derivative(p Points, per int64) Points {
+	return Points{}
+}
+
+func (m mathStub) Rolling(p Points, f AggregateFunc, w Window) Points {
+	return Points{}
+}
+
+func (m mathStub) RollingContext(ctx context.Context, p Points, f AggregateFunc, w Window) Points {
+	Checkpoint(ctx)
+	return m.Rolling(p, f, w)
+}
+
+func (m mathStub) Outliers(p Points, d OutlierDetector) (clean, flagged Points) {
+	return Points{}, Points{}
+}
+
+func (m mathStub) OutliersContext(ctx context.Context, p Points, d OutlierDetector) (clean, flagged Points) {
+	Checkpoint(ctx)
+	return m.Outliers(p, d)
+}
+
+func (m mathStub) Integrate(p Points, period int64, rule IntegrationRule) Points {
+	return Points{}
+}
+
+func (m mathStub) Histogram(p Points, b Binning) Histogram {
+	return Histogram{}
+}
+
+func (m mathStub) LoadDurationCurve(p Points, period, width int64) DurationCurve {
+	return DurationCurve{}
+}
+
+func (m mathStub) Correlate(a, b Points, method CorrelationMethod) (float64, bool) {
+	return 0, false
+}
+
+func (m mathStub) Covariance(a, b Points) (float64, bool) {
+	return 0, false
+}
+
+func (m mathStub) LinearFit(a, b Points) (LinearFit, bool) {
+	return LinearFit{}, false
+}
+
+func (m mathStub) Scatter(a, b Points, width int64) ScatterPoints {
+	return ScatterPoints{}
+}
+
+func (m mathStub) Forecast(p Points, model ForecastModel, step, horizon int64) Forecast {
+	return Forecast{Points: Points{}, Lower: Points{}, Upper: Points{}}
+}
+
+func (m mathStub) ForecastContext(ctx context.Context, p Points, model ForecastModel, step, horizon int64) Forecast {
+	Checkpoint(ctx)
+	return m.Forecast(p, model, step, horizon)
+}
+
+func (m mathStub) Quality(p Points, resolution int64, r Interval, location *time.Location) QualityReport {
+	return QualityReport{}
+}
+
+func (m mathStub) Sort(p Points) Points {
+	return Points{}
+}
+
+func (m mathStub) Dedupe(p Points, keep DedupeMode) Points {
+	return Points{}
+}
+
+func (m mathStub) Merge(g Group) Points {
+	return Points{}
+}
+
+func (m mathStub) Align(g Group, join JoinMode, fill int64) Group {
+	return Group{}
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVEncoder is implemented by tabular types which can be rendered as text/csv by Response, like Table.
+type CSVEncoder interface {
+	// WriteCSV writes the comma separated values including a header. Times are formatted in the given location.
+	WriteCSV(w io.Writer, location *time.Location) error
+}
+
+// NDJSONEncoder is implemented by point series which can be rendered as application/x-ndjson by Response, like
+// Points or FPoints. The format is the same as the PointStream of the REST API.
+type NDJSONEncoder interface {
+	// WriteNDJSON writes a json object per line.
+	WriteNDJSON(w io.Writer) error
+}
+
+// WriteNDJSON writes each point as a newline delimited json object, like {"x":1653988963,"y":42}.
+func (p Points) WriteNDJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, point := range p {
+		if err := enc.Encode(point); err != nil {
+			return fmt.Errorf("cannot encode point: %w", err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// WriteNDJSON writes each point as a newline delimited json object, like {"x":1653988963000,"y":4.2}.
+func (p FPoints) WriteNDJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, point := range p {
+		if err := enc.Encode(point); err != nil {
+			return fmt.Errorf("cannot encode point: %w", err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// mediaRange is a parsed element of an Accept header.
+type mediaRange struct {
+	typ string
+	q   float64
+}
+
+// negotiate returns the best supported media type for the given value and Accept header. See also Response.
+func negotiate(accept string, v interface{}) string {
+	for _, r := range parseAccept(accept) {
+		switch r.typ {
+		case "*/*", "application/*":
+			return defaultMimeType(v)
+		case mimeTypeJSON:
+			return mimeTypeJSON
+		case mimeTypeXML, "text/xml":
+			return mimeTypeXML
+		case mimeTypeCSV, "text/*":
+			if _, ok := v.(CSVEncoder); ok {
+				return mimeTypeCSV
+			}
+		case mimeTypeNDJSON:
+			if _, ok := v.(NDJSONEncoder); ok {
+				return mimeTypeNDJSON
+			}
+		}
+	}
+
+	return defaultMimeType(v)
+}
+
+// defaultMimeType returns xml if the first field of a struct has a xml-tag and json otherwise.
+func defaultMimeType(v interface{}) string {
+	if typ := reflect.TypeOf(v); typ != nil && typ.Kind() == reflect.Struct && typ.NumField() > 0 {
+		if _, isXML := typ.Field(0).Tag.Lookup("xml"); isXML {
+			return mimeTypeXML
+		}
+	}
+
+	return mimeTypeJSON
+}
+
+// parseAccept returns the acceptable media ranges sorted descending by their quality. Ranges with a quality of
+// zero and unparsable ranges are ignored.
+func parseAccept(accept string) []mediaRange {
+	var res []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		typ, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(s, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+
+		res = append(res, mediaRange{typ: typ, q: q})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].q > res[j].q
+	})
+
+	return res
+}
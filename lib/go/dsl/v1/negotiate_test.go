@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	type xmlResponse struct {
+		Name string `xml:"name"`
+	}
+
+	table := Group{{{X: 0, Y: 1}}}.Pivot([]string{"a"}, nil, FillNull)
+
+	tests := []struct {
+		name   string
+		accept string
+		v      interface{}
+		want   string
+	}{
+		{"missing", "", struct{}{}, mimeTypeJSON},
+		{"legacy-xml", "", xmlResponse{}, mimeTypeXML},
+		{"wildcard-legacy-xml", "*/*", xmlResponse{}, mimeTypeXML},
+		{"explicit-json", "application/json", xmlResponse{}, mimeTypeJSON},
+		{"explicit-xml", "application/xml", struct{}{}, mimeTypeXML},
+		{"csv", "text/csv", table, mimeTypeCSV},
+		{"csv-unsupported", "text/csv", Points{}, mimeTypeJSON},
+		{"ndjson", "application/x-ndjson", Points{}, mimeTypeNDJSON},
+		{"quality", "application/json;q=0.5, text/csv", table, mimeTypeCSV},
+		{"quality-zero", "text/csv;q=0, application/xml;q=0.1", table, mimeTypeXML},
+		{"unsupported", "image/png", Points{}, mimeTypeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiate(tt.accept, tt.v); got != tt.want {
+				t.Errorf("negotiate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	ctx := WithHttpResponse(WithHttpRequest(context.Background(), r), w)
+
+	Attachment(ctx, "export.ndjson", Points{{X: 1, Y: 2}, {X: 3, Y: 4}})
+
+	if got := w.Header().Get(contentType); got != mimeTypeNDJSON {
+		t.Errorf("unexpected content type %v", got)
+	}
+
+	if got := w.Header().Get(contentDisposition); got != `attachment; filename=export.ndjson` {
+		t.Errorf("unexpected content disposition %v", got)
+	}
+
+	if got := w.Body.String(); got != "{\"x\":1,\"y\":2}\n{\"x\":3,\"y\":4}\n" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import "fmt"
+
+// DedupeMode is an enum like type which defines which point is kept by Points.Dedupe, if multiple points
+// share the same X value.
+type DedupeMode int
+
+// Valid determines if DedupeMode defines a valid enum.
+// See also KeepFirst, KeepLast, KeepMin and KeepMax.
+func (m DedupeMode) Valid() bool {
+	return m >= KeepFirst && m <= KeepMax
+}
+
+const (
+	// KeepFirst keeps the first point in order of the series.
+	KeepFirst DedupeMode = iota + 1
+
+	// KeepLast keeps the last point in order of the series, which is usually the latest correction.
+	KeepLast
+
+	// KeepMin keeps the point with the smallest Y value.
+	KeepMin
+
+	// KeepMax keeps the point with the largest Y value.
+	KeepMax
+)
+
+// An OrderError describes the first violation of the strictly ascending order of a series.
+// See also Points.Validate.
+type OrderError struct {
+	// Index is the position of the offending point.
+	Index int
+
+	// Prev is the predecessor of the offending point.
+	Prev Point
+
+	// Point is the offending point.
+	Point Point
+}
+
+// Error returns a human-readable description of the violation.
+func (e OrderError) Error() string {
+	if e.Prev.X == e.Point.X {
+		return fmt.Sprintf("points are not unique: duplicate x=%d at index %d", e.Point.X, e.Index)
+	}
+
+	return fmt.Sprintf("points are not sorted: x=%d at index %d follows x=%d", e.Point.X, e.Index, e.Prev.X)
+}
+
+// Validate checks that the points are sorted strictly ascending by X, which is expected by most other operations.
+// Returns an OrderError describing the first violation, which is either an out-of-order or a duplicate X value.
+// See also Sort, Dedupe and Quality.
+func (p Points) Validate() error {
+	for i := 1; i < len(p); i++ {
+		if p[i].X <= p[i-1].X {
+			return OrderError{Index: i, Prev: p[i-1], Point: p[i]}
+		}
+	}
+
+	return nil
+}
+
+// Sort sorts the points ascending by X in-place. The sort is stable, so the order of points with the same X value is
+// kept, which is important for a subsequent Dedupe with KeepFirst or KeepLast.
+func (p Points) Sort() Points {
+	return Math.Sort(p)
+}
+
+// Dedupe removes all points with a duplicate X value, so that only the point defined by the mode is kept.
+// Example:
+//  [(0|5), (600|7), (600|3), (600|9), (1200|1)]
+//  => KeepFirst: [(0|5), (600|7), (1200|1)]
+//  => KeepLast:  [(0|5), (600|9), (1200|1)]
+//  => KeepMin:   [(0|5), (600|3), (1200|1)]
+// It expects that points are ordered ascended by X (==time). The result is undefined, if the dataset is not
+// sorted correctly, so use Sort before if required.
+func (p Points) Dedupe(keep DedupeMode) Points {
+	return Math.Dedupe(p, keep)
+}
+
+// Merge combines this series with the given fallback series into a single sorted series without duplicates.
+// For each X value, the point of the first series containing that X wins, so this series has the highest priority
+// and the fallbacks are used in the given order. Example, to stitch the data of a replacement sensor into the gaps
+// of the primary sensor:
+//  primary:     [(0|5),         (1200|7)]
+//  replacement: [(0|4), (600|6), (1200|8), (1800|9)]
+//  => [(0|5), (600|6), (1200|7), (1800|9)]
+// All series must be sorted strictly ascending by X, otherwise the result is undefined. See also Group.Merge.
+func (p Points) Merge(fallbacks ...Points) Points {
+	return Math.Merge(append(Group{p}, fallbacks...))
+}
+
+// Merge combines all series of the group into a single sorted series, where the order of the group defines the
+// priority. See also Points.Merge.
+func (p Group) Merge() Points {
+	return Math.Merge(p)
+}
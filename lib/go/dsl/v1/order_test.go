@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPoints_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		p         Points
+		wantIndex int
+	}{
+		{"empty", nil, -1},
+		{"sorted", Points{{X: 0}, {X: 600}, {X: 1200}}, -1},
+		{"duplicate", Points{{X: 0}, {X: 600}, {X: 600}}, 2},
+		{"unsorted", Points{{X: 0}, {X: 1200}, {X: 600}}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.p.Validate()
+			if tt.wantIndex < 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+
+				return
+			}
+
+			var orderErr OrderError
+			if !errors.As(err, &orderErr) {
+				t.Fatalf("Validate() error = %v, want OrderError", err)
+			}
+
+			if orderErr.Index != tt.wantIndex {
+				t.Errorf("Validate() index = %v, want %v", orderErr.Index, tt.wantIndex)
+			}
+		})
+	}
+}
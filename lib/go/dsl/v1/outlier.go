@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import "context"
+
+// OutlierMethod is an enum like type to identify an algorithm for the detection of outliers.
+// See also OutlierDetector.
+type OutlierMethod int
+
+// Valid determines if OutlierMethod defines a valid enum.
+// See also OutlierZScore, OutlierTukey, OutlierHampel and OutlierFlatLine.
+func (m OutlierMethod) Valid() bool {
+	return m >= OutlierZScore && m <= OutlierFlatLine
+}
+
+const (
+	// OutlierZScore flags all points whose distance to the mean is larger than K times the standard deviation of
+	// the entire series. Note, that large outliers also inflate the standard deviation, so a K of 3 is a typical
+	// value.
+	OutlierZScore OutlierMethod = iota + 1
+
+	// OutlierTukey flags all points outside of the fences [Q1 - K*IQR, Q3 + K*IQR] of the entire series, where IQR is
+	// the interquartile range Q3 - Q1. A K of 1.5 is the classic value and 3 is used for "far out" values.
+	OutlierTukey
+
+	// OutlierHampel flags all points whose distance to the median of the surrounding Window is larger than K times
+	// the scaled median absolute deviation (MAD * 1.4826) of that window. In contrast to OutlierZScore and
+	// OutlierTukey, this detects local spikes, even if the level of the series changes over time.
+	OutlierHampel
+
+	// OutlierFlatLine flags all points of a run whose Y values differ by no more than Tolerance from the first
+	// value of the run, if the run covers at least the Window, e.g. TimeWindow(3*3600, WindowLeft). This detects
+	// frozen or stuck sensors. The alignment of the window is ignored.
+	OutlierFlatLine
+)
+
+// OutlierDetector configures the detection of outliers. Use the according constructors ZScore, Tukey, Hampel or
+// FlatLine to create a valid configuration.
+type OutlierDetector struct {
+	// Method defines the algorithm.
+	Method OutlierMethod
+
+	// K is the threshold factor for OutlierZScore, OutlierTukey and OutlierHampel.
+	K float64
+
+	// Window is the moving window for OutlierHampel and the minimum length of a run for OutlierFlatLine.
+	Window Window
+
+	// Tolerance is the maximum deviation of a pre-scaled Y value which is still considered to be flat
+	// by OutlierFlatLine.
+	Tolerance int64
+}
+
+// ZScore returns an OutlierDetector for the OutlierZScore method.
+func ZScore(k float64) OutlierDetector {
+	return OutlierDetector{Method: OutlierZScore, K: k}
+}
+
+// Tukey returns an OutlierDetector for the OutlierTukey method.
+func Tukey(k float64) OutlierDetector {
+	return OutlierDetector{Method: OutlierTukey, K: k}
+}
+
+// Hampel returns an OutlierDetector for the OutlierHampel method.
+func Hampel(w Window, k float64) OutlierDetector {
+	return OutlierDetector{Method: OutlierHampel, Window: w, K: k}
+}
+
+// FlatLine returns an OutlierDetector for the OutlierFlatLine method.
+func FlatLine(minLength Window, tolerance int64) OutlierDetector {
+	return OutlierDetector{Method: OutlierFlatLine, Window: minLength, Tolerance: tolerance}
+}
+
+// Outliers splits the series into the points which have not been flagged by the given detector and the points which
+// have been flagged. Both series keep their original order and points, so the flagged series can be displayed
+// as an additional marker series, e.g. in a data quality dashboard.
+//
+// It expects that points are ordered ascended by X (==time). The result is undefined, if the dataset is not
+// sorted correctly. See also DropOutliers.
+func (p Points) Outliers(d OutlierDetector) (clean, flagged Points) {
+	return Math.Outliers(p, d)
+}
+
+// OutliersContext is like Outliers but the detection performs a Checkpoint regularly, so that a windowed detector,
+// like OutlierHampel, over a year of raw data is aborted, as soon as the context is done or its budget is exceeded.
+func (p Points) OutliersContext(ctx context.Context, d OutlierDetector) (clean, flagged Points) {
+	return Math.OutliersContext(ctx, p, d)
+}
+
+// DropOutliers returns only those points which have not been flagged by the given detector. In contrast to Limit,
+// the thresholds are derived from the data itself. See also Outliers.
+func (p Points) DropOutliers(d OutlierDetector) Points {
+	clean, _ := p.Outliers(d)
+	return clean
+}
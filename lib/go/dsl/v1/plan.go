@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CalendarUnit is an enum like type which defines the calendar grouping of a Plan.
+type CalendarUnit int
+
+// Valid determines if CalendarUnit defines a valid enum.
+// See also CalendarDay, CalendarMonth and CalendarYear.
+func (u CalendarUnit) Valid() bool {
+	return u >= CalendarDay && u <= CalendarYear
+}
+
+const (
+	// CalendarDay groups like Points.GroupByDay.
+	CalendarDay CalendarUnit = iota + 1
+
+	// CalendarMonth groups like Points.GroupByMonth.
+	CalendarMonth
+
+	// CalendarYear groups like Points.GroupByYear.
+	CalendarYear
+)
+
+// A Plan declares a query as a whole, so that a DB implementation can push the grouping, reduction and
+// downscaling down to its storage instead of loading all raw points. A Plan is built fluently, where each step
+// returns a modified copy. Example, to load the daily energy of a portfolio:
+//  plan := Select(energy).
+//    From(devices...).
+//    Between(Time(ctx).ThisMonth()).
+//    GroupBy(CalendarDay, NoDrift, Timezone(ctx)).
+//    Reduce(SumY)
+//  daily := Query(ctx).Execute(plan)
+// See also DB.Execute and Plan.Eval.
+type Plan struct {
+	// MetricIDs contains the selected metrics.
+	MetricIDs UUIDs
+
+	// BucketIDs contains the selected buckets.
+	BucketIDs UUIDs
+
+	// Interval is the inclusive range of the raw points to load.
+	Interval Interval
+
+	// Calendar is the grouping unit or zero, if the points are not grouped.
+	Calendar CalendarUnit
+
+	// Drift is applied before grouping, just like the GroupBy* functions do.
+	Drift int64
+
+	// Location is the time zone of the calendar grouping.
+	Location *time.Location
+
+	// Aggregate reduces each group into a single point or is zero, if nothing is reduced. Without a Calendar,
+	// each series is reduced into a single point.
+	Aggregate AggregateFunc
+
+	// Algorithm is the downscaling applied at last or zero, if nothing is downscaled.
+	Algorithm DownscaleAlgorithm
+
+	// Width is the amount of pixels for the downscaling.
+	Width int64
+}
+
+// Select starts a new Plan for the given metrics. The identifiers are copied.
+func Select(metricIDs ...UUID) Plan {
+	return Plan{MetricIDs: append(UUIDs(nil), metricIDs...)}
+}
+
+// From selects the buckets to query. The identifiers are copied, so the caller may reuse its slice.
+func (p Plan) From(bucketIDs ...UUID) Plan {
+	p.BucketIDs = append(UUIDs(nil), bucketIDs...)
+	return p
+}
+
+// Between sets the inclusive interval to query.
+func (p Plan) Between(r Interval) Plan {
+	p.Interval = r
+	return p
+}
+
+// GroupBy groups the points of each series by the calendar unit within the given location. The drift is added
+// to each timestamp before grouping, e.g. SamplingSpec.Drift. Each group is aligned to its natural start, like
+// AlignGroupStart does. A group must be reduced, see also Reduce.
+func (p Plan) GroupBy(unit CalendarUnit, drift int64, location *time.Location) Plan {
+	p.Calendar = unit
+	p.Drift = drift
+	p.Location = location
+	return p
+}
+
+// Reduce applies the aggregate function on each group or on the entire series, if not grouped.
+func (p Plan) Reduce(f AggregateFunc) Plan {
+	p.Aggregate = f
+	return p
+}
+
+// Downscale applies the given algorithm with the given width on each resulting series, see also
+// Points.DownscaleWith.
+func (p Plan) Downscale(algorithm DownscaleAlgorithm, width int64) Plan {
+	p.Algorithm = algorithm
+	p.Width = width
+	return p
+}
+
+// Validate checks if the plan is complete and consistent.
+func (p Plan) Validate() error {
+	if len(p.MetricIDs) == 0 {
+		return fmt.Errorf("plan selects no metrics")
+	}
+
+	if len(p.BucketIDs) == 0 {
+		return fmt.Errorf("plan selects no buckets")
+	}
+
+	if p.Interval.Min > p.Interval.Max {
+		return fmt.Errorf("plan interval is empty: %d > %d", p.Interval.Min, p.Interval.Max)
+	}
+
+	if p.Calendar != 0 {
+		if !p.Calendar.Valid() {
+			return fmt.Errorf("invalid calendar unit: %d", p.Calendar)
+		}
+
+		if p.Location == nil {
+			return fmt.Errorf("calendar grouping requires a location")
+		}
+
+		if p.Aggregate == 0 {
+			return fmt.Errorf("calendar grouping requires an aggregate function")
+		}
+	}
+
+	if p.Aggregate != 0 && !p.Aggregate.Valid() {
+		return fmt.Errorf("invalid aggregate function: %d", p.Aggregate)
+	}
+
+	if p.Algorithm != 0 {
+		if !p.Algorithm.Valid() {
+			return fmt.Errorf("invalid downscale algorithm: %d", p.Algorithm)
+		}
+
+		if p.Width <= 0 {
+			return fmt.Errorf("downscale width must be positive: %d", p.Width)
+		}
+	}
+
+	return nil
+}
+
+// Eval is the reference execution of the plan, which loads the raw points using DB.FindAllInRange and applies
+// each step using the intrinsics. It is used by the DB returned by Query, if DB.Execute has no pushdown.
+// The result contains one series per bucket and metric, just like DB.FindAllInRange. Panics, if the plan is
+// not valid.
+func (p Plan) Eval(db DB) SeriesGroup {
+	return p.eval(context.Background(), db)
+}
+
+// eval is like Eval but performs a Checkpoint before each series.
+func (p Plan) eval(ctx context.Context, db DB) SeriesGroup {
+	if err := p.Validate(); err != nil {
+		panic(fmt.Errorf("cannot evaluate plan: %w", err))
+	}
+
+	return db.FindAllInRange(p.BucketIDs, p.MetricIDs, p.Interval).ForEachContext(ctx, p.apply)
+}
+
+// apply executes the grouping, reduction and downscaling on a single series.
+func (p Plan) apply(pts Points) Points {
+	switch {
+	case p.Calendar == CalendarDay:
+		pts = pts.GroupByDay(p.Drift, AlignGroupStart, p.Location).Reduce(p.Aggregate)
+	case p.Calendar == CalendarMonth:
+		pts = pts.GroupByMonth(p.Drift, AlignGroupStart, p.Location).Reduce(p.Aggregate)
+	case p.Calendar == CalendarYear:
+		pts = pts.GroupByYear(p.Drift, AlignGroupStart, p.Location).Reduce(p.Aggregate)
+	case p.Aggregate != 0:
+		pts = Group{pts}.Reduce(p.Aggregate)
+	}
+
+	if p.Algorithm != 0 {
+		pts = pts.DownscaleWith(p.Algorithm, p.Width)
+	}
+
+	return pts
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlan_Validate(t *testing.T) {
+	metric, bucket := NewUUID(), NewUUID()
+	base := Select(metric).From(bucket).Between(Interval{Min: 0, Max: 100})
+
+	tests := []struct {
+		name    string
+		plan    Plan
+		wantErr bool
+	}{
+		{"select", base, false},
+		{"grouped", base.GroupBy(CalendarDay, NoDrift, time.UTC).Reduce(SumY), false},
+		{"reduced", base.Reduce(AvgY), false},
+		{"downscaled", base.Downscale(DownscaleLTTB, 800), false},
+		{"no-metrics", Select().From(bucket), true},
+		{"no-buckets", Select(metric), true},
+		{"empty-interval", base.Between(Interval{Min: 100, Max: 0}), true},
+		{"invalid-calendar", base.GroupBy(42, NoDrift, time.UTC).Reduce(SumY), true},
+		{"no-location", base.GroupBy(CalendarMonth, NoDrift, nil).Reduce(SumY), true},
+		{"no-reduce", base.GroupBy(CalendarYear, NoDrift, time.UTC), true},
+		{"invalid-reduce", base.Reduce(42), true},
+		{"invalid-algorithm", base.Downscale(42, 800), true},
+		{"no-width", base.Downscale(DownscaleM4, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.plan.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlan_Builder(t *testing.T) {
+	metric, bucket := NewUUID(), NewUUID()
+	base := Select(metric).From(bucket)
+	grouped := base.GroupBy(CalendarMonth, -600, time.UTC).Reduce(MaxY)
+
+	if base.Calendar != 0 || base.Aggregate != 0 {
+		t.Errorf("expected builder to return modified copies but got %+v", base)
+	}
+
+	devices := []UUID{bucket}
+	fromDevices := base.From(devices...)
+	devices[0] = NewUUID()
+	if fromDevices.BucketIDs[0] != bucket {
+		t.Errorf("expected builder to copy the bucket identifiers")
+	}
+
+	if grouped.Calendar != CalendarMonth || grouped.Drift != -600 || grouped.Aggregate != MaxY {
+		t.Errorf("unexpected plan %+v", grouped)
+	}
+}
+
+func TestPlan_Eval(t *testing.T) {
+	db := newTestDB()
+	a, b, power := NewUUID(), NewUUID(), NewUUID()
+	db.put(a, power, Points{{X: 1, Y: 2}, {X: 50, Y: 3}})
+
+	q := Query(testContext(db, nil))
+	res := q.Execute(Select(power).From(a, b).Between(Interval{Min: 0, Max: 10}))
+	if len(res) != 2 {
+		t.Fatalf("expected 2 series but got %d", len(res))
+	}
+
+	if s, ok := res.Find(a, power); !ok || len(s.Points) != 1 {
+		t.Errorf("unexpected series %+v", s)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for invalid plan")
+		}
+	}()
+
+	q.Execute(Select(power))
+}
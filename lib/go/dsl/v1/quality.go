@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import "time"
+
+// Completeness relates the amount of expected points within an Interval to the amount of actually available points.
+type Completeness struct {
+	Interval Interval `json:"interval"`
+	Expected int64    `json:"expected"`
+	Actual   int64    `json:"actual"`
+}
+
+// Percent returns the availability between 0 and 100. An interval without any expected points is complete.
+func (c Completeness) Percent() float64 {
+	if c.Expected <= 0 {
+		return 100
+	}
+
+	return float64(c.Actual) / float64(c.Expected) * 100
+}
+
+// A QualityReport describes the data quality of a series compared to its expected resolution.
+// See also Points.Quality.
+type QualityReport struct {
+	// Completeness covers the entire analyzed interval and is the usual availability number of a report.
+	Completeness
+
+	// Resolution is the expected distance between two points in seconds.
+	Resolution int64 `json:"resolution"`
+
+	// Missing contains the inclusive intervals of all expected but missing points in ascending order.
+	Missing []Interval `json:"missing"`
+
+	// Duplicates contains all points whose X value has already been seen before.
+	Duplicates Points `json:"duplicates"`
+
+	// OutOfOrder contains all points whose X value is smaller than the X value of their predecessor.
+	OutOfOrder Points `json:"outOfOrder"`
+
+	// Daily contains the completeness per day within the location, in ascending order.
+	Daily []Completeness `json:"daily"`
+
+	// Monthly contains the completeness per month within the location, in ascending order.
+	Monthly []Completeness `json:"monthly"`
+
+	// LongestGap is the longest interval of Missing. It is only valid, if HasGaps returns true.
+	LongestGap Interval `json:"longestGap"`
+}
+
+// HasGaps returns true, if there is at least a single missing point.
+func (r QualityReport) HasGaps() bool {
+	return len(r.Missing) > 0
+}
+
+// Sorted returns true, if the series has neither duplicates nor points out of order, which is required by most
+// other operations on Points.
+func (r QualityReport) Sorted() bool {
+	return len(r.Duplicates) == 0 && len(r.OutOfOrder) == 0
+}
+
+// ResolutionSeconds returns the Resolution in seconds, as required by Points.Quality.
+func (m Metric) ResolutionSeconds() int64 {
+	return int64(m.Resolution / time.Second)
+}
+
+// Quality analyzes the series against the given resolution in seconds within the inclusive interval. A point is
+// expected at each multiple of the resolution within the interval, so that e.g. a day of 10 minute values expects
+// 144 points. Points whose X is not a multiple of the resolution are counted for the expected slot they fall into,
+// just like SnapToGrid does. Points outside of the interval are ignored. The daily and monthly completeness is
+// calculated within the given location, so that days with a daylight saving time change expect 138 or 150 points.
+//
+// In contrast to other operations, the series does not need to be sorted, because detecting the order is part
+// of the analysis. Example, to report the availability of a metric:
+//  report := pts.Quality(metric.ResolutionSeconds(), r, Timezone(ctx))
+//  availability := report.Percent()
+func (p Points) Quality(resolution int64, r Interval, location *time.Location) QualityReport {
+	return Math.Quality(p, resolution, r, location)
+}
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import "context"
+
+// A Series is a labeled (time) series, which keeps the identity of its bucket and metric and the scale of its
+// values. In contrast to a Group, the relation between a series and its bucket cannot get lost, even if a bucket
+// has no data. See also DB.FindSeriesInRange.
+type Series struct {
+	BucketID UUID   `json:"bucketId"`
+	MetricID UUID   `json:"metricId"`
+	Scale    int64  `json:"scale"`
+	Points   Points `json:"points"`
+}
+
+// Scaled returns the points together with their scale.
+func (s Series) Scaled() ScaledPoints {
+	return ScaledPoints{Points: s.Points, Scale: s.Scale}
+}
+
+// SeriesGroup is a slice of labeled series. It provides the same pipeline as Group but keeps the labels.
+type SeriesGroup []Series
+
+// Group returns the unlabeled points of each series. The points are not copied.
+func (g SeriesGroup) Group() Group {
+	res := make(Group, 0, len(g))
+	for _, s := range g {
+		res = append(res, s.Points)
+	}
+
+	return res
+}
+
+// BucketIDs returns the bucket identifier of each series in order.
+func (g SeriesGroup) BucketIDs() UUIDs {
+	res := make(UUIDs, 0, len(g))
+	for _, s := range g {
+		res = append(res, s.BucketID)
+	}
+
+	return res
+}
+
+// BucketNames returns the translated bucket name of each series in order. See also BucketNames.
+func (g SeriesGroup) BucketNames(ctx context.Context) []string {
+	return BucketNames(ctx, g.BucketIDs())
+}
+
+// Metric returns all series of the given metric in order, e.g. to select a metric from the result of
+// DB.FindAllInRange. The points are not copied.
+func (g SeriesGroup) Metric(metricID UUID) SeriesGroup {
+	res := SeriesGroup{}
+	for _, s := range g {
+		if s.MetricID == metricID {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+// Bucket returns all series of the given bucket in order. The points are not copied.
+func (g SeriesGroup) Bucket(bucketID UUID) SeriesGroup {
+	res := SeriesGroup{}
+	for _, s := range g {
+		if s.BucketID == bucketID {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+// Find returns the first series of the given bucket and metric or false, if no such series is contained.
+func (g SeriesGroup) Find(bucketID, metricID UUID) (Series, bool) {
+	for _, s := range g {
+		if s.BucketID == bucketID && s.MetricID == metricID {
+			return s, true
+		}
+	}
+
+	return Series{}, false
+}
+
+// Align is like Group.Align and aligns all series to a common X axis but keeps the labels. Example, to calculate
+// the specific yield per device:
+//  all := db.FindAllInRange(devices, UUIDs{energy, capacity}, r)
+//  for _, id := range devices {
+//    pair := all.Bucket(id).Align(InnerJoin, 0)
+//    yield := pair[0].Points.Div(pair[1].Points, opts)
+//  }
+func (g SeriesGroup) Align(join JoinMode, fill int64) SeriesGroup {
+	res := make(SeriesGroup, len(g))
+	copy(res, g)
+	for i, pts := range g.Group().Align(join, fill) {
+		res[i].Points = pts
+	}
+
+	return res
+}
+
+// ForEach is like Group.ForEach and allows an in-line modification of the points of each series, but keeps the
+// labels. Example:
+//  db.FindSeriesInRange(devices, metric, r).ForEach(func(pts Points) Points {
+//    return pts.GroupByDay(NoDrift, AlignGroupStart, loc).Reduce(AvgY)
+//  })
+func (g SeriesGroup) ForEach(f func(pts Points) Points) SeriesGroup {
+	for i, s := range g {
+		g[i].Points = f(s.Points)
+	}
+
+	return g
+}
+
+// ForEachContext is like ForEach but performs a Checkpoint before each series. See also Group.ForEachContext.
+func (g SeriesGroup) ForEachContext(ctx context.Context, f func(pts Points) Points) SeriesGroup {
+	for i, s := range g {
+		Checkpoint(ctx)
+		g[i].Points = f(s.Points)
+	}
+
+	return g
+}
+
+// Reduce applies the AggregateFunc on the points of each series and returns a labeled value per series in order.
+// See also Points.Reduce.
+func (g SeriesGroup) Reduce(f AggregateFunc) []SeriesValue {
+	res := make([]SeriesValue, 0, len(g))
+	for _, s := range g {
+		v, ok := s.Points.Reduce(f)
+		res = append(res, SeriesValue{
+			BucketID: s.BucketID,
+			MetricID: s.MetricID,
+			Scale:    s.Scale,
+			Value:    v,
+			Valid:    ok,
+		})
+	}
+
+	return res
+}
+
+// ReduceTransposed is like Group.ReduceTransposed and combines all series into a single series, e.g. to calculate
+// a portfolio. The labels of the individual series are lost, as intended.
+func (g SeriesGroup) ReduceTransposed(f AggregateFunc) Points {
+	return g.Group().ReduceTransposed(f)
+}
+
+// Unscale converts each series into FPoints using its own scale and attaches the translated bucket name, as returned
+// by BucketNames. This should be the last step after Downsampling and performs another allocation.
+func (g SeriesGroup) Unscale(ctx context.Context) FSeriesGroup {
+	return g.ForEachF(ctx, func(s Series) FPoints {
+		return s.Scaled().Unscale()
+	})
+}
+
+// ForEachF is like Group.ForEachF but keeps the labels and attaches the translated bucket name, as returned by
+// BucketNames.
+func (g SeriesGroup) ForEachF(ctx context.Context, f func(s Series) FPoints) FSeriesGroup {
+	names := g.BucketNames(ctx)
+	res := make(FSeriesGroup, 0, len(g))
+	for i, s := range g {
+		res = append(res, FSeries{
+			BucketID: s.BucketID,
+			MetricID: s.MetricID,
+			Name:     names[i],
+			Points:   f(s),
+		})
+	}
+
+	return res
+}
+
+// A SeriesValue is the labeled result of reducing a Series. Valid is false, if the series could not be reduced,
+// e.g. because it was empty.
+type SeriesValue struct {
+	BucketID UUID  `json:"bucketId"`
+	MetricID UUID  `json:"metricId"`
+	Scale    int64 `json:"scale"`
+	Value    int64 `json:"value"`
+	Valid    bool  `json:"valid"`
+}
+
+// FSeries is the labeled and display ready representation of a Series. See also FPoint.
+type FSeries struct {
+	BucketID UUID    `json:"bucketId"`
+	MetricID UUID    `json:"metricId"`
+	Name     string  `json:"name"`
+	Points   FPoints `json:"points"`
+}
+
+// FSeriesGroup is just a slice of FSeries elements.
+type FSeriesGroup []FSeries
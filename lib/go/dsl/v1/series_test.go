@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"testing"
+)
+
+func TestSeriesGroup_Unscale(t *testing.T) {
+	db := newTestDB()
+	known, unknown, empty, metric := NewUUID(), NewUUID(), NewUUID(), NewUUID()
+	db.buckets[known] = Bucket{
+		ID:           known,
+		Name:         "turbine",
+		Translations: map[string]Translation{"de": {Name: "Anlage"}},
+	}
+	db.buckets[empty] = Bucket{ID: empty, Name: "empty"}
+	db.metrics[metric] = Metric{ID: metric, Scale: 10}
+	db.put(known, metric, Points{{X: 1, Y: 15}})
+	db.put(unknown, metric, Points{{X: 2, Y: 25}})
+
+	ctx := testContext(db, map[string]string{"Accept-Language": "de"})
+	g := db.FindSeriesInRange([]UUID{known, unknown, empty}, metric, Interval{Min: 0, Max: 10})
+	res := g.ForEach(func(pts Points) Points {
+		return pts
+	}).Unscale(ctx)
+
+	if len(res) != 3 {
+		t.Fatalf("expected 3 series but got %d", len(res))
+	}
+
+	want := []struct {
+		id   UUID
+		name string
+		pts  int
+	}{
+		{known, "Anlage", 1},
+		{unknown, unknown.String(), 1},
+		{empty, "empty", 0},
+	}
+
+	for i, w := range want {
+		if res[i].BucketID != w.id || res[i].Name != w.name || len(res[i].Points) != w.pts {
+			t.Errorf("series %d: got %+v, want %+v", i, res[i], w)
+		}
+	}
+
+	if y := res[0].Points[0].Y; y != 1.5 {
+		t.Errorf("expected unscaled value 1.5 but got %v", y)
+	}
+}
+
+func TestSeriesGroup_Select(t *testing.T) {
+	db := newTestDB()
+	a, b, power, wind := NewUUID(), NewUUID(), NewUUID(), NewUUID()
+	db.metrics[power] = Metric{ID: power, Scale: 1000}
+	db.put(a, power, Points{{X: 1, Y: 1500}})
+	db.put(a, wind, Points{{X: 1, Y: 7}})
+	db.put(b, power, Points{{X: 1, Y: 900}})
+
+	all := db.FindAllInRange([]UUID{a, b}, []UUID{power, wind}, Interval{Min: 0, Max: 10})
+	if len(all) != 4 {
+		t.Fatalf("expected 4 series but got %d", len(all))
+	}
+
+	if got := all.Metric(power).BucketIDs(); len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("unexpected buckets of power %v", got)
+	}
+
+	if got := all.Bucket(b); len(got) != 2 || got[0].MetricID != power || got[1].MetricID != wind {
+		t.Errorf("unexpected series of bucket b %+v", got)
+	}
+
+	if s, ok := all.Find(a, power); !ok || s.Scale != 1000 || s.Points[0].Y != 1500 {
+		t.Errorf("unexpected series %+v", s)
+	}
+
+	if s, ok := all.Find(b, wind); !ok || len(s.Points) != 0 {
+		t.Errorf("expected empty series but got %+v", s)
+	}
+
+	if _, ok := all.Find(NewUUID(), wind); ok {
+		t.Errorf("expected unknown bucket to be missing")
+	}
+}
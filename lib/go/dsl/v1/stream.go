@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A Stream writes a response incrementally, so that large results, like a year of raw data of hundreds of
+// devices, never need to be kept in memory as a whole. Each write is flushed immediately, which causes a chunked
+// transfer encoding. The format is negotiated once by OpenStream and is either application/x-ndjson (default) or
+// text/csv. A Stream is not safe for concurrent use. Example:
+//  s := OpenStream(ctx)
+//  defer s.Close()
+//  for _, month := range months {
+//    t := db.FindSeriesInRange(devices, metric, month).Pivot(ctx, FillNull)
+//    if !s.WriteTable(t) {
+//      return // client has gone away
+//    }
+//  }
+type Stream struct {
+	ctx      context.Context
+	req      context.Context
+	buf      *bufio.Writer
+	flusher  http.Flusher
+	mimeType string
+	location *time.Location
+	csv      *csv.Writer
+	json     *json.Encoder
+	header   []string
+	err      error
+}
+
+// streamCheckpointRows is the amount of rows after which a Stream performs another Checkpoint.
+const streamCheckpointRows = 4096
+
+// OpenStream starts a streaming response, as an alternative to Response. The format is negotiated from the Accept
+// header of the request and is text/csv, if requested, and application/x-ndjson otherwise. The stream ends early
+// and gracefully, as soon as the context of the request is done, which is the case if the client disconnects.
+// Each write performs a Checkpoint on the given context, so that an exceeded deadline or budget aborts the
+// evaluation. Close must be called after the last write.
+func OpenStream(ctx context.Context) *Stream {
+	w, ok := ctx.Value(ctxHttpResponseWriter).(http.ResponseWriter)
+	if !ok || w == nil {
+		panic("context does not contain a http.ResponseWriter")
+	}
+
+	s := &Stream{ctx: ctx, req: ctx, buf: bufio.NewWriter(w), mimeType: mimeTypeNDJSON}
+	s.flusher, _ = w.(http.Flusher)
+
+	if req, ok := ctx.Value(ctxHttpRequest).(*http.Request); ok && req != nil {
+		s.req = req.Context()
+		for _, r := range parseAccept(req.Header.Get("Accept")) {
+			if r.typ == mimeTypeCSV || r.typ == mimeTypeNDJSON {
+				s.mimeType = r.typ
+				break
+			}
+		}
+	}
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if s.mimeType == mimeTypeCSV {
+		s.location = Timezone(ctx)
+		s.csv = csv.NewWriter(s.buf)
+		w.Header().Set(contentType, mimeTypeCSV+"; charset=utf-8")
+	} else {
+		s.json = json.NewEncoder(s.buf)
+		w.Header().Set(contentType, mimeTypeNDJSON)
+	}
+
+	return s
+}
+
+// MimeType returns the negotiated format of the stream.
+func (s *Stream) MimeType() string {
+	return s.mimeType
+}
+
+// Err returns the reason why the stream has ended early, e.g. context.Canceled if the client has disconnected.
+// Returns nil, as long as the stream is still writable.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// WritePoints writes and flushes the given points. In NDJSON, each point is written like Points.WriteNDJSON does.
+// In CSV, a header (time,y) is written before the first row and the values are written as they are, so use
+// WriteTable for unscaled and labeled values. Returns false, if the stream has ended and the kernel should stop.
+func (s *Stream) WritePoints(pts Points) bool {
+	if !s.begin([]string{"time", "y"}) {
+		return false
+	}
+
+	for i, point := range pts {
+		if i > 0 && i%streamCheckpointRows == 0 && !s.checkpoint() {
+			return false
+		}
+
+		var err error
+		if s.csv != nil {
+			err = s.csv.Write([]string{formatDateTime(point.X, s.location), strconv.FormatInt(point.Y, 10)})
+		} else {
+			err = s.json.Encode(point)
+		}
+
+		if s.fail(err) {
+			return false
+		}
+	}
+
+	return s.flush()
+}
+
+// WriteTable writes and flushes the rows of the given table. In NDJSON, each row is written as a TableRow json
+// object. In CSV, the header is written before the first row, just like Table.WriteCSV does. A CSV stream can
+// be continued with subsequent tables, e.g. one per month, as long as they have the same column names.
+// Returns false, if the stream has ended and the kernel should stop.
+func (s *Stream) WriteTable(t Table) bool {
+	header := make([]string, 0, len(t.Columns)+1)
+	header = append(header, "time")
+	for _, col := range t.Columns {
+		header = append(header, col.Name)
+	}
+
+	if !s.begin(header) {
+		return false
+	}
+
+	if s.csv != nil {
+		record := make([]string, 0, len(header))
+		for row := range t.X {
+			if row > 0 && row%streamCheckpointRows == 0 && !s.checkpoint() {
+				return false
+			}
+
+			record = t.appendCSVRow(record[:0], row, s.location)
+			if s.fail(s.csv.Write(record)) {
+				return false
+			}
+		}
+	} else {
+		for i, row := range t.AsRows().Rows {
+			if i > 0 && i%streamCheckpointRows == 0 && !s.checkpoint() {
+				return false
+			}
+
+			if s.fail(s.json.Encode(row)) {
+				return false
+			}
+		}
+	}
+
+	return s.flush()
+}
+
+// Close flushes any pending data. It does not close the underlying connection, which is up to the server.
+func (s *Stream) Close() {
+	if s.err == nil {
+		s.flush()
+	}
+}
+
+// begin checks if the stream can still be written, performs a checkpoint and writes the csv header, if not yet
+// done. Panics, if a different csv header has already been written. NDJSON has no header, so points and tables can
+// be mixed.
+func (s *Stream) begin(header []string) bool {
+	if !s.checkpoint() {
+		return false
+	}
+
+	if s.csv == nil {
+		return true
+	}
+
+	if s.header != nil {
+		if !equalStrings(s.header, header) {
+			panic(fmt.Errorf("cannot continue stream with columns %v: already started with %v", header, s.header))
+		}
+
+		return true
+	}
+
+	s.header = header
+	return !s.fail(s.csv.Write(header))
+}
+
+// checkpoint returns false, if the stream has ended, e.g. because the client has disconnected, and performs a
+// Checkpoint otherwise. The stream is checked first, because the kernel context is usually derived from the
+// request context and a disconnect must end the stream gracefully instead of aborting the evaluation.
+func (s *Stream) checkpoint() bool {
+	if s.done() {
+		return false
+	}
+
+	Checkpoint(s.ctx)
+	return true
+}
+
+// done returns true, if the stream has ended due to a write error or because the client has disconnected.
+func (s *Stream) done() bool {
+	if s.err != nil {
+		return true
+	}
+
+	if err := s.req.Err(); err != nil {
+		s.err = err
+		return true
+	}
+
+	return false
+}
+
+// flush writes all buffered data to the client and returns false, if the stream has ended.
+func (s *Stream) flush() bool {
+	if s.csv != nil {
+		s.csv.Flush()
+		if s.fail(s.csv.Error()) {
+			return false
+		}
+	}
+
+	if s.fail(s.buf.Flush()) {
+		return false
+	}
+
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+
+	return !s.done()
+}
+
+// fail records the first error, after which the stream has ended, and returns true if err is not nil.
+func (s *Stream) fail(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if s.err == nil {
+		s.err = err
+	}
+
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		write  func(s *Stream) bool
+		want   string
+		mime   string
+	}{
+		{
+			name:   "ndjson-points",
+			accept: "",
+			write: func(s *Stream) bool {
+				return s.WritePoints(Points{{X: 1, Y: 2}}) && s.WritePoints(Points{{X: 3, Y: 4}})
+			},
+			want: "{\"x\":1,\"y\":2}\n{\"x\":3,\"y\":4}\n",
+			mime: mimeTypeNDJSON,
+		},
+		{
+			name:   "csv-tables",
+			accept: "text/csv",
+			write: func(s *Stream) bool {
+				a := Group{{{X: 0, Y: 15}}}.Pivot([]string{"a"}, []int64{10}, FillNull)
+				b := Group{{{X: 60}}}.Pivot([]string{"a"}, []int64{10}, FillNull)
+				return s.WriteTable(a) && s.WriteTable(b)
+			},
+			want: "time,a\n1970-01-01 00:00:00,1.5\n1970-01-01 00:01:00,0.0\n",
+			mime: mimeTypeCSV + "; charset=utf-8",
+		},
+		{
+			name:   "ndjson-mixed",
+			accept: "application/x-ndjson",
+			write: func(s *Stream) bool {
+				t := Group{{{X: 1, Y: 15}}}.Pivot([]string{"a"}, []int64{10}, FillNull)
+				return s.WritePoints(Points{{X: 1, Y: 2}}) && s.WriteTable(t)
+			},
+			want: "{\"x\":1,\"y\":2}\n{\"x\":1000,\"values\":[1.5]}\n",
+			mime: mimeTypeNDJSON,
+		},
+		{
+			name:   "ndjson-table",
+			accept: "application/x-ndjson",
+			write: func(s *Stream) bool {
+				return s.WriteTable(Group{{{X: 1, Y: 15}}}.Pivot([]string{"a"}, []int64{10}, FillNull))
+			},
+			want: "{\"x\":1000,\"values\":[1.5]}\n",
+			mime: mimeTypeNDJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept", tt.accept)
+			r.Header.Set("X-TZ", "UTC")
+			w := httptest.NewRecorder()
+			ctx := WithHttpResponse(WithHttpRequest(context.Background(), r), w)
+
+			s := OpenStream(ctx)
+			if !tt.write(s) {
+				t.Fatalf("stream ended early: %v", s.Err())
+			}
+
+			s.Close()
+
+			if got := w.Header().Get(contentType); got != tt.mime {
+				t.Errorf("unexpected content type %v", got)
+			}
+
+			if !w.Flushed {
+				t.Errorf("expected stream to be flushed")
+			}
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("unexpected body %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("GET", "/", nil).WithContext(reqCtx)
+	w := httptest.NewRecorder()
+	ctx := WithHttpResponse(WithHttpRequest(context.Background(), r), w)
+
+	s := OpenStream(ctx)
+	if !s.WritePoints(Points{{X: 1, Y: 2}}) {
+		t.Fatalf("stream ended early: %v", s.Err())
+	}
+
+	cancel()
+
+	if s.WritePoints(Points{{X: 3, Y: 4}}) {
+		t.Fatalf("expected stream to end after the client has gone away")
+	}
+
+	if s.Err() != context.Canceled {
+		t.Errorf("unexpected error %v", s.Err())
+	}
+
+	s.Close()
+
+	if got := w.Body.String(); got != "{\"x\":1,\"y\":2}\n" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
+
+// cancelingRecorder cancels the request as soon as the first buffered chunk reaches the client.
+type cancelingRecorder struct {
+	*httptest.ResponseRecorder
+	cancel context.CancelFunc
+}
+
+func (w cancelingRecorder) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestStreamCanceledDerived(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("GET", "/", nil).WithContext(reqCtx)
+	r.Header.Set("Accept", "text/csv")
+	r.Header.Set("X-TZ", "UTC")
+	w := cancelingRecorder{ResponseRecorder: httptest.NewRecorder(), cancel: cancel}
+	ctx := WithHttpResponse(WithHttpRequest(r.Context(), r), w)
+
+	pts := make(Points, 3*streamCheckpointRows)
+	for i := range pts {
+		pts[i] = Point{X: int64(i), Y: int64(i)}
+	}
+
+	s := OpenStream(ctx)
+	if s.WriteTable(Group{pts}.Pivot([]string{"a"}, []int64{1}, FillNull)) {
+		t.Fatalf("expected stream to end after the client has gone away")
+	}
+
+	if s.Err() != context.Canceled {
+		t.Errorf("unexpected error %v", s.Err())
+	}
+
+	s.Close()
+}
+
+func TestStreamColumnMismatch(t *testing.T) {
+	s := OpenStream(testContext(newTestDB(), map[string]string{"Accept": "text/csv", "X-TZ": "UTC"}))
+	s.WriteTable(Group{{}}.Pivot([]string{"a"}, nil, FillNull))
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+
+	s.WriteTable(Group{{}}.Pivot([]string{"b"}, nil, FillNull))
+}
@@ -0,0 +1,271 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// FillMode is an enum like type which defines how missing cells of a Table are filled.
+type FillMode int
+
+// Valid determines if FillMode defines a valid enum.
+// See also FillNull, FillForward and FillZero.
+func (m FillMode) Valid() bool {
+	return m >= FillNull && m <= FillZero
+}
+
+const (
+	// FillNull keeps missing cells empty, which renders as null in json and as an empty field in csv.
+	FillNull FillMode = iota + 1
+
+	// FillForward repeats the last available value of the same column. Cells before the first value stay empty.
+	FillForward
+
+	// FillZero sets missing cells to zero.
+	FillZero
+)
+
+// A Column is a named and scaled column of a Table. A cell is empty, if it is not Valid.
+type Column struct {
+	Name   string
+	Scale  int64
+	Values []int64
+	Valid  []bool
+}
+
+// A Table is the wide representation of multiple series on a common X axis, with one row per X value and one
+// column per series, as usually expected by spreadsheets. It renders as json like AsColumns, so use AsRows
+// explicitly for the row oriented json representation. See also Group.Pivot.
+type Table struct {
+	// X contains the sorted and unique X values of all rows, usually in seconds since Unix Epoch.
+	X []int64
+
+	// Columns contains a column per series, each with a value per row.
+	Columns []Column
+}
+
+// Pivot creates a Table from the series of the group, using the given column names and scales in the same order.
+// The X axis is the union of all X values and missing cells are filled according to the given mode. If multiple
+// points of a series share the same X value, the first one wins. If scales is nil, a scale of 1 is used.
+// Each series must be sorted ascending by X, otherwise the result is undefined. Panics, if the amount of names
+// or scales does not match the amount of series.
+func (p Group) Pivot(names []string, scales []int64, fill FillMode) Table {
+	return p.pivot(context.Background(), names, scales, fill)
+}
+
+// pivot is like Pivot but performs a Checkpoint before each column.
+func (p Group) pivot(ctx context.Context, names []string, scales []int64, fill FillMode) Table {
+	if len(names) != len(p) || (scales != nil && len(scales) != len(p)) {
+		panic(fmt.Errorf("cannot pivot %d series with %d names and %d scales", len(p), len(names), len(scales)))
+	}
+
+	var xs []int64
+	for _, pts := range p {
+		for _, point := range pts {
+			xs = append(xs, point.X)
+		}
+	}
+
+	sort.Slice(xs, func(i, j int) bool {
+		return xs[i] < xs[j]
+	})
+
+	unique := xs[:0]
+	for i, x := range xs {
+		if i == 0 || x != xs[i-1] {
+			unique = append(unique, x)
+		}
+	}
+
+	t := Table{X: unique, Columns: make([]Column, 0, len(p))}
+	for i, pts := range p {
+		Checkpoint(ctx)
+		col := Column{
+			Name:   names[i],
+			Scale:  1,
+			Values: make([]int64, len(unique)),
+			Valid:  make([]bool, len(unique)),
+		}
+
+		if scales != nil {
+			col.Scale = normScale(scales[i])
+		}
+
+		j := 0
+		for row, x := range unique {
+			for j < len(pts) && pts[j].X < x {
+				j++
+			}
+
+			switch {
+			case j < len(pts) && pts[j].X == x:
+				col.Values[row] = pts[j].Y
+				col.Valid[row] = true
+			case fill == FillForward && row > 0:
+				col.Values[row] = col.Values[row-1]
+				col.Valid[row] = col.Valid[row-1]
+			case fill == FillZero:
+				col.Valid[row] = true
+			}
+		}
+
+		t.Columns = append(t.Columns, col)
+	}
+
+	return t
+}
+
+// Pivot creates a Table using the translated bucket names as column names and the scale of each series.
+// In contrast to Group.Pivot, it performs a Checkpoint before each column. See also Group.Pivot.
+func (g SeriesGroup) Pivot(ctx context.Context, fill FillMode) Table {
+	scales := make([]int64, 0, len(g))
+	for _, s := range g {
+		scales = append(scales, s.Scale)
+	}
+
+	return g.Group().pivot(ctx, g.BucketNames(ctx), scales, fill)
+}
+
+// WriteCSV writes the table as comma separated values including a header. The first column contains the X values
+// formatted as local date and time (2006-01-02 15:04:05) in the given location and all values are written as exact
+// decimals using their scale, so that spreadsheets can import them without rounding errors.
+func (t Table) WriteCSV(w io.Writer, location *time.Location) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, 0, len(t.Columns)+1)
+	record = append(record, "time")
+	for _, col := range t.Columns {
+		record = append(record, col.Name)
+	}
+
+	if err := cw.Write(record); err != nil {
+		return fmt.Errorf("cannot write csv header: %w", err)
+	}
+
+	for row := range t.X {
+		record = t.appendCSVRow(record[:0], row, location)
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("cannot write csv row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("cannot flush csv: %w", err)
+	}
+
+	return nil
+}
+
+// appendCSVRow appends the fields of the given row to the record, as written by WriteCSV.
+func (t Table) appendCSVRow(record []string, row int, location *time.Location) []string {
+	record = append(record, formatDateTime(t.X[row], location))
+	for _, col := range t.Columns {
+		if !col.Valid[row] {
+			record = append(record, "")
+			continue
+		}
+
+		record = append(record, NewDecimal(col.Values[row], col.Scale).String())
+	}
+
+	return record
+}
+
+// formatDateTime formats the unix timestamp in seconds as local date and time of the location for csv.
+func formatDateTime(x int64, location *time.Location) string {
+	return time.Unix(x, 0).In(location).Format(dateTimeFormat)
+}
+
+// AsRows returns the row oriented json representation of the table. Just like FPoint, X is converted into
+// milliseconds and the values are unscaled.
+func (t Table) AsRows() TableRows {
+	res := TableRows{
+		Columns: make([]string, 0, len(t.Columns)),
+		Rows:    make([]TableRow, 0, len(t.X)),
+	}
+
+	for _, col := range t.Columns {
+		res.Columns = append(res.Columns, col.Name)
+	}
+
+	for row, x := range t.X {
+		values := make([]*float64, 0, len(t.Columns))
+		for _, col := range t.Columns {
+			values = append(values, col.unscaled(row))
+		}
+
+		res.Rows = append(res.Rows, TableRow{X: x * 1000, Values: values})
+	}
+
+	return res
+}
+
+// AsColumns returns the column oriented json representation of the table, which is more compact and usually
+// preferred by chart libraries. Just like FPoint, X is converted into milliseconds and the values are unscaled.
+func (t Table) AsColumns() TableColumns {
+	res := TableColumns{
+		X:       make([]int64, 0, len(t.X)),
+		Columns: make([]TableColumn, 0, len(t.Columns)),
+	}
+
+	for _, x := range t.X {
+		res.X = append(res.X, x*1000)
+	}
+
+	for _, col := range t.Columns {
+		values := make([]*float64, 0, len(t.X))
+		for row := range t.X {
+			values = append(values, col.unscaled(row))
+		}
+
+		res.Columns = append(res.Columns, TableColumn{Name: col.Name, Values: values})
+	}
+
+	return res
+}
+
+// MarshalJSON encodes the column oriented json representation, see also AsColumns.
+func (t Table) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.AsColumns())
+}
+
+func (c Column) unscaled(row int) *float64 {
+	if !c.Valid[row] {
+		return nil
+	}
+
+	v := NewDecimal(c.Values[row], c.Scale).Float64()
+	return &v
+}
+
+// TableRows is the row oriented json representation of a Table.
+type TableRows struct {
+	Columns []string   `json:"columns"`
+	Rows    []TableRow `json:"rows"`
+}
+
+// TableRow contains the X value in milliseconds and a value per column, which is null if the cell is empty.
+type TableRow struct {
+	X      int64      `json:"x"`
+	Values []*float64 `json:"values"`
+}
+
+// TableColumns is the column oriented json representation of a Table.
+type TableColumns struct {
+	X       []int64       `json:"x"`
+	Columns []TableColumn `json:"columns"`
+}
+
+// TableColumn contains the name and a value per row, which is null if the cell is empty.
+type TableColumn struct {
+	Name   string     `json:"name"`
+	Values []*float64 `json:"values"`
+}
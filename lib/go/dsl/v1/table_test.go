@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testGroup() Group {
+	return Group{
+		{{X: 0, Y: 15}, {X: 1200, Y: 25}},
+		{{X: 600, Y: 3}, {X: 1200, Y: 4}},
+	}
+}
+
+func TestGroup_Pivot(t *testing.T) {
+	tests := []struct {
+		name string
+		fill FillMode
+		want string
+	}{
+		{"null", FillNull, "time,a,b\n1970-01-01 00:00:00,1.5,\n1970-01-01 00:10:00,,3\n1970-01-01 00:20:00,2.5,4\n"},
+		{"forward", FillForward, "time,a,b\n1970-01-01 00:00:00,1.5,\n1970-01-01 00:10:00,1.5,3\n1970-01-01 00:20:00,2.5,4\n"},
+		{"zero", FillZero, "time,a,b\n1970-01-01 00:00:00,1.5,0\n1970-01-01 00:10:00,0.0,3\n1970-01-01 00:20:00,2.5,4\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := testGroup().Pivot([]string{"a", "b"}, []int64{10, 1}, tt.fill)
+			var buf bytes.Buffer
+			if err := table.WriteCSV(&buf, time.UTC); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteCSV() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTable_JSON(t *testing.T) {
+	table := testGroup().Pivot([]string{"a", "b"}, nil, FillNull)
+
+	buf, err := json.Marshal(table.AsRows())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const wantRows = `{"columns":["a","b"],"rows":[{"x":0,"values":[15,null]},{"x":600000,"values":[null,3]},` +
+		`{"x":1200000,"values":[25,4]}]}`
+	if string(buf) != wantRows {
+		t.Errorf("AsRows() = %s, want %s", buf, wantRows)
+	}
+
+	buf, err = json.Marshal(table.AsColumns())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const wantColumns = `{"x":[0,600000,1200000],"columns":[{"name":"a","values":[15,null,25]},` +
+		`{"name":"b","values":[null,3,4]}]}`
+	if string(buf) != wantColumns {
+		t.Errorf("AsColumns() = %s, want %s", buf, wantColumns)
+	}
+}
+
+func TestTable_Response(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	ctx := WithHttpResponse(WithHttpRequest(context.Background(), r), w)
+
+	Response(ctx, testGroup().Pivot([]string{"a", "b"}, []int64{10, 1}, FillNull))
+
+	if got := w.Header().Get(contentType); got != mimeTypeJSON {
+		t.Errorf("unexpected content type %v", got)
+	}
+
+	const want = `{"x":[0,600000,1200000],"columns":[{"name":"a","values":[1.5,null,2.5]},` +
+		`{"name":"b","values":[null,3,4]}]}` + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected body %q, want %q", got, want)
+	}
+}
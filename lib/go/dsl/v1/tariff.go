@@ -0,0 +1,311 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+// ClockTime is an unparsed wall clock time of a day in the format hh:mm, like 08:00 or 20:30. The special value
+// 24:00 denotes the end of a day. A ClockTime does not carry a time zone, because it is always interpreted
+// in the Timezone of the calculation, which respects daylight saving times.
+type ClockTime string
+
+// UnmarshalJSON validates the clock time during unmarshalling.
+func (c *ClockTime) UnmarshalJSON(bytes []byte) error {
+	s, err := strconv.Unquote(string(bytes))
+	if err != nil {
+		return fmt.Errorf("cannot unquote clock time: %w", err)
+	}
+
+	if _, err := ClockTime(s).Minutes(); err != nil {
+		return err
+	}
+
+	*c = ClockTime(s)
+	return nil
+}
+
+// Minutes parses the clock time and returns the minutes since midnight, in the range of 0 to 1440 (inclusive).
+func (c ClockTime) Minutes() (int, error) {
+	s := string(c)
+	if len(s) != 5 || s[2] != ':' {
+		return 0, fmt.Errorf("invalid clock time '%s': expected hh:mm", s)
+	}
+
+	h, err := strconv.Atoi(s[:2])
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock time '%s': %w", s, err)
+	}
+
+	m, err := strconv.Atoi(s[3:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock time '%s': %w", s, err)
+	}
+
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid clock time '%s': out of range", s)
+	}
+
+	return h*60 + m, nil
+}
+
+// Date is an unparsed calendar date in the format yyyy-mm-dd, like 2022-12-25. A Date does not carry a time zone,
+// because it is always interpreted in the Timezone of the calculation.
+type Date string
+
+// UnmarshalJSON validates the date during unmarshalling.
+func (d *Date) UnmarshalJSON(bytes []byte) error {
+	s, err := strconv.Unquote(string(bytes))
+	if err != nil {
+		return fmt.Errorf("cannot unquote date: %w", err)
+	}
+
+	if _, err := time.Parse(dateFormat, s); err != nil {
+		return fmt.Errorf("invalid date '%s': %w", s, err)
+	}
+
+	*d = Date(s)
+	return nil
+}
+
+// DateOf returns the Date of the given time instant within its location.
+func DateOf(t time.Time) Date {
+	return Date(t.Format(dateFormat))
+}
+
+// A TariffWindow describes a labeled and recurring time range of a day, like a peak tariff from 08:00 to 20:00 on
+// weekdays. If From is after To, the window crosses midnight, like an off-peak tariff from 22:00 to 06:00.
+type TariffWindow struct {
+	// Label is the name of the window, like peak or off-peak. Multiple windows may share the same label.
+	Label string `json:"label"`
+
+	// Weekdays restricts the window to the given days (0 is Sunday). If empty, every day matches. For windows which
+	// cross midnight, the weekday of the instant to evaluate is used.
+	Weekdays []time.Weekday `json:"weekdays"`
+
+	// From is the inclusive start of the window.
+	From ClockTime `json:"from"`
+
+	// To is the exclusive end of the window.
+	To ClockTime `json:"to"`
+}
+
+// A Schedule is a declarative definition of labeled time windows, like time-of-use tariffs. The windows are
+// evaluated in order and the first matching window wins. Any instant which is not covered by a window gets the
+// Default label. All windows are evaluated in the wall clock time of a location, usually as returned by
+// Timezone, so the windows follow the daylight saving times.
+//
+// Example of a typical peak/off-peak tariff, where holidays are treated like a Sunday:
+//  {
+//    "default": "off-peak",
+//    "windows": [{"label": "peak", "weekdays": [1,2,3,4,5], "from": "08:00", "to": "20:00"}],
+//    "holidays": ["2022-12-25", "2022-12-26"],
+//    "holidayAs": 0
+//  }
+type Schedule struct {
+	// Windows are evaluated in order.
+	Windows []TariffWindow `json:"windows"`
+
+	// Default is the label of all instants which are not covered by any window.
+	Default string `json:"default"`
+
+	// Holidays contains the local dates which are evaluated as if they were the HolidayAs weekday.
+	// See also Calendar.
+	Holidays []Date `json:"holidays"`
+
+	// Calendar optionally provides additional holidays, e.g. the public holidays of the buckets region.
+	// See also RegionCalendar.
+	Calendar *Calendar `json:"calendar,omitempty"`
+
+	// HolidayAs defines the weekday which is used for evaluating holidays. The zero value is Sunday.
+	HolidayAs time.Weekday `json:"holidayAs"`
+}
+
+// Labels returns the distinct labels of the schedule in the order of their first occurrence, followed by the
+// Default label.
+func (s Schedule) Labels() []string {
+	labels := make([]string, 0, len(s.Windows)+1)
+	seen := map[string]bool{}
+	for _, w := range s.Windows {
+		if !seen[w.Label] {
+			seen[w.Label] = true
+			labels = append(labels, w.Label)
+		}
+	}
+
+	if !seen[s.Default] {
+		labels = append(labels, s.Default)
+	}
+
+	return labels
+}
+
+// Validate checks that all windows contain valid clock times.
+func (s Schedule) Validate() error {
+	_, err := s.Compile()
+	return err
+}
+
+// Label returns the label for the given unix timestamp in seconds, evaluated in the given location.
+// Panics if the schedule is invalid. The schedule is compiled and validated on each call, so use Split or
+// ReduceByDay for series and MustCompile to label many single timestamps.
+func (s Schedule) Label(x int64, location *time.Location) string {
+	return s.MustCompile().Label(x, location)
+}
+
+// Split partitions the points by the label of their X value, evaluated in the given location. The result contains
+// one series per label in the order of Labels, even if a series is empty. The order of the points is kept, so each
+// series can be grouped further, e.g. by using GroupByDay. Panics if the schedule is invalid.
+func (s Schedule) Split(p Points, location *time.Location) (labels []string, g Group) {
+	c := s.MustCompile()
+	labels = s.Labels()
+	idx := make(map[string]int, len(labels))
+	g = make(Group, len(labels))
+	for i, label := range labels {
+		idx[label] = i
+		g[i] = Points{}
+	}
+
+	for _, point := range p {
+		i := idx[c.label(time.Unix(point.X, 0).In(location))]
+		g[i] = append(g[i], point)
+	}
+
+	return labels, g
+}
+
+// ReduceByDay splits the points using Split and reduces each label per day of the given location, e.g. to calculate
+// the peak and off-peak consumption per day using SumY. The X value of each reduced point is the start of the day.
+func (s Schedule) ReduceByDay(p Points, f AggregateFunc, location *time.Location) (labels []string, g Group) {
+	labels, g = s.Split(p, location)
+	g.ForEach(func(pts Points) Points {
+		return pts.GroupByDay(NoDrift, AlignGroupStart, location).Reduce(f)
+	})
+
+	return labels, g
+}
+
+// ReduceByMonth is like ReduceByDay but reduces each label per month, as usually required for billing.
+func (s Schedule) ReduceByMonth(p Points, f AggregateFunc, location *time.Location) (labels []string, g Group) {
+	labels, g = s.Split(p, location)
+	g.ForEach(func(pts Points) Points {
+		return pts.GroupByMonth(NoDrift, AlignGroupStart, location).Reduce(f)
+	})
+
+	return labels, g
+}
+
+type compiledWindow struct {
+	label    string
+	weekdays [7]bool
+	from, to int
+}
+
+// A CompiledSchedule is the validated and preprocessed form of a Schedule, which labels timestamps without
+// parsing the windows or building the holidays again. See also Schedule.Compile.
+type CompiledSchedule struct {
+	windows   []compiledWindow
+	def       string
+	holidays  map[Date]bool
+	calendar  *Calendar
+	holidayAs time.Weekday
+}
+
+// MustCompile is like Compile but panics if the schedule is invalid.
+func (s Schedule) MustCompile() CompiledSchedule {
+	c, err := s.Compile()
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// Compile validates the schedule and returns a form which can be reused to label many timestamps. Example:
+//  c := schedule.MustCompile()
+//  for _, point := range pts {
+//    label := c.Label(point.X, loc)
+//    ...
+//  }
+func (s Schedule) Compile() (CompiledSchedule, error) {
+	c := CompiledSchedule{
+		def:       s.Default,
+		holidays:  make(map[Date]bool, len(s.Holidays)),
+		calendar:  s.Calendar,
+		holidayAs: s.HolidayAs,
+	}
+
+	if s.Calendar != nil {
+		if err := s.Calendar.Validate(); err != nil {
+			return c, err
+		}
+	}
+
+	for _, date := range s.Holidays {
+		c.holidays[date] = true
+	}
+
+	for i, w := range s.Windows {
+		from, err := w.From.Minutes()
+		if err != nil {
+			return c, fmt.Errorf("invalid window %d: %w", i, err)
+		}
+
+		to, err := w.To.Minutes()
+		if err != nil {
+			return c, fmt.Errorf("invalid window %d: %w", i, err)
+		}
+
+		cw := compiledWindow{label: w.Label, from: from, to: to}
+		for _, day := range w.Weekdays {
+			if day < time.Sunday || day > time.Saturday {
+				return c, fmt.Errorf("invalid window %d: invalid weekday %d", i, day)
+			}
+
+			cw.weekdays[day] = true
+		}
+
+		if len(w.Weekdays) == 0 {
+			cw.weekdays = [7]bool{true, true, true, true, true, true, true}
+		}
+
+		c.windows = append(c.windows, cw)
+	}
+
+	return c, nil
+}
+
+// Label returns the label for the given unix timestamp in seconds, evaluated in the given location.
+func (c CompiledSchedule) Label(x int64, location *time.Location) string {
+	return c.label(time.Unix(x, 0).In(location))
+}
+
+func (c CompiledSchedule) label(t time.Time) string {
+	weekday := t.Weekday()
+	if c.holidays[DateOf(t)] || (c.calendar != nil && c.calendar.IsHoliday(t)) {
+		weekday = c.holidayAs
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range c.windows {
+		if !w.weekdays[weekday] {
+			continue
+		}
+
+		if w.from <= w.to {
+			if minute >= w.from && minute < w.to {
+				return w.label
+			}
+		} else if minute >= w.from || minute < w.to {
+			return w.label
+		}
+	}
+
+	return c.def
+}
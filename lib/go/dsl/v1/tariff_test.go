@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testSchedule(t *testing.T) Schedule {
+	t.Helper()
+
+	const doc = `{
+		"default": "off-peak",
+		"windows": [
+			{"label": "peak", "weekdays": [1,2,3,4,5], "from": "08:00", "to": "20:00"},
+			{"label": "night", "from": "22:00", "to": "06:00"}
+		],
+		"holidays": ["2022-12-26"],
+		"holidayAs": 0
+	}`
+
+	var s Schedule
+	if err := json.Unmarshal([]byte(doc), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestSchedule_Label(t *testing.T) {
+	s := testSchedule(t)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{"monday-peak", time.Date(2022, 12, 19, 8, 0, 0, 0, berlin), "peak"},
+		{"monday-end-exclusive", time.Date(2022, 12, 19, 20, 0, 0, 0, berlin), "off-peak"},
+		{"saturday", time.Date(2022, 12, 17, 12, 0, 0, 0, berlin), "off-peak"},
+		{"holiday", time.Date(2022, 12, 26, 12, 0, 0, 0, berlin), "off-peak"},
+		{"night-before-midnight", time.Date(2022, 12, 19, 23, 0, 0, 0, berlin), "night"},
+		{"night-after-midnight", time.Date(2022, 12, 20, 5, 59, 0, 0, berlin), "night"},
+		// 06:30 UTC is 08:30 in summer (CEST) but only 07:30 in winter (CET)
+		{"dst-summer", time.Date(2022, 7, 4, 6, 30, 0, 0, time.UTC), "peak"},
+		{"dst-winter", time.Date(2022, 12, 19, 6, 30, 0, 0, time.UTC), "off-peak"},
+	}
+
+	c := s.MustCompile()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Label(tt.t.Unix(), berlin); got != tt.want {
+				t.Errorf("Label() = %v, want %v", got, tt.want)
+			}
+
+			if got := c.Label(tt.t.Unix(), berlin); got != tt.want {
+				t.Errorf("CompiledSchedule.Label() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_Split(t *testing.T) {
+	s := testSchedule(t)
+	monday := time.Date(2022, 12, 19, 0, 0, 0, 0, time.UTC).Unix()
+	p := Points{
+		{X: monday + 7*3600, Y: 1},
+		{X: monday + 8*3600, Y: 2},
+		{X: monday + 21*3600, Y: 3},
+		{X: monday + 23*3600, Y: 4},
+	}
+
+	labels, g := s.Split(p, time.UTC)
+	if len(labels) != 3 || labels[0] != "peak" || labels[1] != "night" || labels[2] != "off-peak" {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+
+	want := []Points{{p[1]}, {p[3]}, {p[0], p[2]}}
+	for i := range want {
+		if len(g[i]) != len(want[i]) {
+			t.Fatalf("%s: got %v, want %v", labels[i], g[i], want[i])
+		}
+
+		for j := range want[i] {
+			if g[i][j] != want[i][j] {
+				t.Fatalf("%s: got %v, want %v", labels[i], g[i], want[i])
+			}
+		}
+	}
+}
+
+func TestSchedule_Invalid(t *testing.T) {
+	var c ClockTime
+	if err := json.Unmarshal([]byte(`"24:01"`), &c); err == nil {
+		t.Fatal("expected error")
+	}
+
+	s := Schedule{Windows: []TariffWindow{{From: "8:00", To: "20:00"}}}
+	if err := s.Validate(); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+// PerSecond represents the 1 literal to improve readability of Points.Rate and Points.Derivative.
+const PerSecond = 1
+
+// PerHour represents the amount of seconds of an hour to improve readability of Points.Rate and Points.Derivative.
+const PerHour = 3600
+
+// CounterMode is an enum like type which defines how a decreasing value of a running counter is interpreted.
+// See also Counter.
+type CounterMode int
+
+// Valid determines if CounterMode defines a valid enum.
+// See also CounterGauge, CounterReset, CounterWrap and CounterDrop.
+func (m CounterMode) Valid() bool {
+	return m >= CounterGauge && m <= CounterDrop
+}
+
+const (
+	// CounterGauge does not detect anything, so a decrease results in a negative delta.
+	CounterGauge CounterMode = iota + 1
+
+	// CounterReset interprets any decrease as a reset to zero, so the delta is the current value.
+	CounterReset
+
+	// CounterWrap interprets any decrease as a wrap-around at Counter.Max, so the delta is
+	// Max - previous + current + 1. If Max is not positive, CounterReset applies.
+	CounterWrap
+
+	// CounterDrop discards the point after a decrease, so that no delta is reported for that interval.
+	CounterDrop
+)
+
+// Counter configures the detection of counter resets and wrap-arounds, e.g. for energy meters which
+// report a running total.
+type Counter struct {
+	// Mode defines how a decrease is interpreted. The zero value is treated as CounterGauge.
+	Mode CounterMode
+
+	// Max is the largest value the counter can hold before it wraps around to zero, e.g. 999999 for a
+	// six-digit meter. Only used by CounterWrap.
+	Max int64
+}
+
+// Gauge is a Counter without any reset or wrap-around detection.
+var Gauge = Counter{Mode: CounterGauge}
+
+// CumSum returns the cumulative sum, so that each Y value is the sum of all previous Y values including itself.
+// It expects that points are ordered ascended by X. Panics with an error wrapping ErrOverflow if the sum overflows.
+func (p Points) CumSum() Points {
+	return Math.CumSum(p)
+}
+
+// Delta returns the difference of each Y value to its preceding neighbor. The resulting series has one point less
+// than the input, and each point has the X value of the later neighbor, so that the delta describes the interval
+// which ends at X. The interpretation of decreasing values is defined by the given Counter. Example for a meter
+// which has been reset:
+//  [(0|100), (600|150), (1200|20), (1800|50)]
+//  => CounterGauge: [(600|50), (1200|-130), (1800|30)]
+//  => CounterReset: [(600|50), (1200|20), (1800|30)]
+//  => CounterDrop:  [(600|50), (1800|30)]
+// It expects that points are ordered ascended by X. The result is undefined, if the dataset is not sorted correctly.
+func (p Points) Delta(c Counter) Points {
+	return Math.Delta(p, c)
+}
+
+// Rate is like Delta, but divides each delta by the elapsed seconds between both neighbors and multiplies it by
+// per, e.g. PerSecond or PerHour. The result is rounded using RoundHalfUp and keeps the scale of the input.
+// Neighbors with the same X are omitted.
+func (p Points) Rate(c Counter, per int64) Points {
+	return Math.Rate(p, c, per)
+}
+
+// Derivative returns the slope between each point and its preceding neighbor, which is delta y / delta x * per,
+// at the X value of the later neighbor. In contrast to Rate, there is no counter detection, so this is intended
+// for gauges like a temperature or a wind speed. The result is rounded using RoundHalfUp and keeps the scale of
+// the input. Neighbors with the same X are omitted.
+func (p Points) Derivative(per int64) Points {
+	return Math.Derivative(p, per)
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Aggregation describes how the values of a Metric have been aggregated from their original source, as defined by
+// the ValueSpec of a Descriptor. A kernel should use it to decide how values can be aggregated further, e.g. power
+// in kW is usually averaged and energy in kWh is usually summed up.
+type Aggregation string
+
+const (
+	// AggregationMax denotes values which are the maximum of their sampling period.
+	AggregationMax Aggregation = "max"
+
+	// AggregationMin denotes values which are the minimum of their sampling period.
+	AggregationMin Aggregation = "min"
+
+	// AggregationAvg denotes values which are the average of their sampling period, like power.
+	AggregationAvg Aggregation = "avg"
+
+	// AggregationSum denotes values which are the sum of their sampling period, like energy.
+	AggregationSum Aggregation = "sum"
+
+	// AggregationNone denotes values which have not been aggregated at all, like an instant measurement.
+	AggregationNone Aggregation = "none"
+
+	// AggregationOther denotes values which have been aggregated using an unspecified function.
+	AggregationOther Aggregation = "other"
+)
+
+// Func returns the AggregateFunc which keeps the semantics of the aggregation, e.g. a sum of sums is still a sum.
+// Returns false for AggregationNone, AggregationOther or unknown values, because there is no natural choice.
+func (a Aggregation) Func() (AggregateFunc, bool) {
+	switch a {
+	case AggregationMax:
+		return MaxY, true
+	case AggregationMin:
+		return MinY, true
+	case AggregationAvg:
+		return AvgY, true
+	case AggregationSum:
+		return SumY, true
+	default:
+		return 0, false
+	}
+}
+
+// Unit is the physical or business unit of a Metric value, like kWh, rpm or km/h. The notation is nearly arbitrary
+// but only the units declared as constants can be converted using ConvertUnit.
+type Unit string
+
+// Units of power.
+const (
+	Watt     Unit = "W"
+	Kilowatt Unit = "kW"
+	Megawatt Unit = "MW"
+	Gigawatt Unit = "GW"
+)
+
+// Units of energy.
+const (
+	WattHour     Unit = "Wh"
+	KilowattHour Unit = "kWh"
+	MegawattHour Unit = "MWh"
+	GigawattHour Unit = "GWh"
+	Joule        Unit = "J"
+	Kilojoule    Unit = "kJ"
+	Megajoule    Unit = "MJ"
+)
+
+// Units of speed.
+const (
+	MeterPerSecond   Unit = "m/s"
+	KilometerPerHour Unit = "km/h"
+	MilePerHour      Unit = "mph"
+)
+
+// Units of length.
+const (
+	Meter     Unit = "m"
+	Kilometer Unit = "km"
+)
+
+// unitDef relates a unit to the base unit of its dimension by the factor num/den.
+type unitDef struct {
+	dimension string
+	num, den  int64
+}
+
+var units = map[Unit]unitDef{
+	Watt:     {"power", 1, 1},
+	Kilowatt: {"power", 1_000, 1},
+	Megawatt: {"power", 1_000_000, 1},
+	Gigawatt: {"power", 1_000_000_000, 1},
+
+	WattHour:     {"energy", 1, 1},
+	KilowattHour: {"energy", 1_000, 1},
+	MegawattHour: {"energy", 1_000_000, 1},
+	GigawattHour: {"energy", 1_000_000_000, 1},
+	Joule:        {"energy", 1, 3_600},
+	Kilojoule:    {"energy", 1_000, 3_600},
+	Megajoule:    {"energy", 1_000_000, 3_600},
+
+	MeterPerSecond:   {"speed", 1, 1},
+	KilometerPerHour: {"speed", 1_000, 3_600},
+	MilePerHour:      {"speed", 1_609_344, 3_600_000},
+
+	Meter:     {"length", 1, 1},
+	Kilometer: {"length", 1_000, 1},
+}
+
+// A Conversion converts a value of one unit into another unit by multiplying with the exact fraction Num/Den.
+// Offsets like between °C and °F are not supported.
+type Conversion struct {
+	From, To Unit
+	Num, Den int64
+}
+
+// ConvertUnit returns the Conversion between the given units. Returns an error, if a unit is unknown or if both
+// units have a different dimension, like kW and kWh. Use Points.Integrate to get from power to energy.
+func ConvertUnit(from, to Unit) (Conversion, error) {
+	f, ok := units[from]
+	if !ok {
+		return Conversion{}, fmt.Errorf("unknown unit '%s'", from)
+	}
+
+	t, ok := units[to]
+	if !ok {
+		return Conversion{}, fmt.Errorf("unknown unit '%s'", to)
+	}
+
+	if f.dimension != t.dimension {
+		return Conversion{}, fmt.Errorf("cannot convert %s (%s) into %s (%s)", from, f.dimension, to, t.dimension)
+	}
+
+	num := new(big.Int).Mul(big.NewInt(f.num), big.NewInt(t.den))
+	den := new(big.Int).Mul(big.NewInt(f.den), big.NewInt(t.num))
+	gcd := new(big.Int).GCD(nil, nil, num, den)
+	num.Quo(num, gcd)
+	den.Quo(den, gcd)
+
+	return Conversion{From: from, To: to, Num: num.Int64(), Den: den.Int64()}, nil
+}
+
+// MustConvertUnit is like ConvertUnit but panics on failure.
+func MustConvertUnit(from, to Unit) Conversion {
+	c, err := ConvertUnit(from, to)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// Apply converts the given value keeping its scale. The result is rounded using the given mode and returns an error
+// wrapping ErrOverflow, if it does not fit into an int64. Use Decimal.Rescale before, if the target unit requires
+// more precision, e.g. when converting from Wh into MWh.
+func (c Conversion) Apply(d Decimal, mode RoundingMode) (Decimal, error) {
+	num := new(big.Int).Mul(big.NewInt(d.Value), big.NewInt(c.Num))
+	v, ok := quoRound(num, big.NewInt(c.Den), mode)
+	if !ok {
+		return Decimal{}, fmt.Errorf("cannot convert %v from %s to %s: %w", d, c.From, c.To, ErrOverflow)
+	}
+
+	return Decimal{Value: v, Scale: d.Scale}, nil
+}
+
+// Convert returns a new series with all Y values converted using the given Conversion. The scale is kept, so use
+// ScaledPoints.Rescale before, if the target unit requires more precision. The original points are not modified.
+func (s ScaledPoints) Convert(c Conversion, mode RoundingMode) (ScaledPoints, error) {
+	res := make(Points, 0, len(s.Points))
+	for _, point := range s.Points {
+		d, err := c.Apply(NewDecimal(point.Y, s.Scale), mode)
+		if err != nil {
+			return ScaledPoints{}, fmt.Errorf("cannot convert point at x=%d: %w", point.X, err)
+		}
+
+		res = append(res, Point{X: point.X, Y: d.Value})
+	}
+
+	return ScaledPoints{Points: res, Scale: s.Scale}, nil
+}
+
+// IntegrationRule is an enum like type to identify the numerical integration rule for Points.Integrate.
+type IntegrationRule int
+
+// Valid determines if IntegrationRule defines a valid enum.
+// See also IntegrateRectangle and IntegrateTrapezoid.
+func (r IntegrationRule) Valid() bool {
+	return r >= IntegrateRectangle && r <= IntegrateTrapezoid
+}
+
+const (
+	// IntegrateRectangle assumes that each value is constant for the entire sampling period, which is the natural
+	// interpretation of period aggregated values, like a 10 minute average of power.
+	IntegrateRectangle IntegrationRule = iota + 1
+
+	// IntegrateTrapezoid assumes that the value changes linearly between two neighbors, which is the natural
+	// interpretation of instant values.
+	IntegrateTrapezoid
+)
+
+// Integrate interprets the Y values as a rate per hour, like power, and returns the integrated quantity per
+// interval, like energy. So kW values become kWh values and the scale is kept. The period is the sampling
+// period in seconds, e.g. 600 for 10 minute values. See also Metric.Resolution.
+//
+// Using IntegrateRectangle, each point results in a point with the same X and Y * period / 3600.
+// Using IntegrateTrapezoid, each pair of neighbors results in a point at the X of the earlier neighbor with
+// (Y1 + Y2) / 2 * (X2 - X1) / 3600. Pairs which are more than period apart are treated as a gap and omitted.
+// Values are rounded using RoundHalfUp. Example for 10 minute power values in kW:
+//  [(0|60), (600|120), (1200|60)]
+//  => IntegrateRectangle: [(0|10), (600|20), (1200|10)]
+//  => IntegrateTrapezoid: [(0|15), (600|15)]
+// Use Points.Reduce or GroupByDay with SumY afterwards, to get the energy of a day.
+//
+// It expects that points are ordered ascended by X (==time). The result is undefined, if the dataset is not
+// sorted correctly.
+func (p Points) Integrate(period int64, rule IntegrationRule) Points {
+	return Math.Integrate(p, period, rule)
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"testing"
+)
+
+func TestConvertUnit(t *testing.T) {
+	tests := []struct {
+		from, to Unit
+		in       Decimal
+		want     Decimal
+		wantErr  bool
+	}{
+		{Kilowatt, Watt, NewDecimal(15, 10), NewDecimal(15000, 10), false},
+		{WattHour, KilowattHour, NewDecimal(1500, 1000), NewDecimal(2, 1000), false},
+		{KilometerPerHour, MeterPerSecond, NewDecimal(360, 10), NewDecimal(100, 10), false},
+		{MeterPerSecond, KilometerPerHour, NewDecimal(100, 10), NewDecimal(360, 10), false},
+		{KilowattHour, Megajoule, NewDecimal(1, 1), NewDecimal(4, 1), false},
+		{MilePerHour, KilometerPerHour, NewDecimal(1000, 100), NewDecimal(1609, 100), false},
+		{Kilowatt, KilowattHour, Decimal{}, Decimal{}, true},
+		{"rpm", "1/s", Decimal{}, Decimal{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			c, err := ConvertUnit(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertUnit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			got, err := c.Apply(tt.in, RoundHalfUp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregation_Func(t *testing.T) {
+	if f, ok := AggregationSum.Func(); !ok || f != SumY {
+		t.Errorf("Func() = %v, %v", f, ok)
+	}
+
+	if _, ok := AggregationOther.Func(); ok {
+		t.Error("other must not have a natural aggregate function")
+	}
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import (
+	"context"
+	"fmt"
+)
+
+// WindowUnit is an enum like type which defines how the size of a Window is measured.
+type WindowUnit int
+
+// Valid determines if WindowUnit defines a valid enum.
+// See also WindowSeconds and WindowPoints.
+func (u WindowUnit) Valid() bool {
+	return u >= WindowSeconds && u <= WindowPoints
+}
+
+const (
+	// WindowSeconds measures the window size as a duration on the X axis, usually in seconds.
+	WindowSeconds WindowUnit = iota + 1
+
+	// WindowPoints measures the window size as an amount of points.
+	WindowPoints
+)
+
+// WindowAlign is an enum like type which defines the position of a Window relative to the point for which
+// the window is evaluated.
+type WindowAlign int
+
+// Valid determines if WindowAlign defines a valid enum.
+// See also WindowLeft, WindowCenter and WindowRight.
+func (a WindowAlign) Valid() bool {
+	return a >= WindowLeft && a <= WindowRight
+}
+
+const (
+	// WindowLeft starts the window at the evaluated point and looks ahead, e.g. [x, x+size).
+	WindowLeft WindowAlign = iota + 1
+
+	// WindowCenter places the evaluated point in the middle of the window, e.g. [x-size/2, x+size/2].
+	WindowCenter
+
+	// WindowRight ends the window at the evaluated point and looks back, e.g. (x-size, x]. This is the usual
+	// trailing window which only depends on past values.
+	WindowRight
+)
+
+// A Window describes a moving window over a series, either by time or by point count. See also TimeWindow and
+// CountWindow.
+type Window struct {
+	// Unit defines the meaning of Size.
+	Unit WindowUnit
+
+	// Size is either the duration in seconds or the amount of points, depending on Unit.
+	Size int64
+
+	// Align defines the position of the window relative to the evaluated point.
+	Align WindowAlign
+}
+
+// TimeWindow returns a Window which covers the given amount of seconds, e.g. 3600 for an hour.
+func TimeWindow(seconds int64, align WindowAlign) Window {
+	return Window{
+		Unit:  WindowSeconds,
+		Size:  seconds,
+		Align: align,
+	}
+}
+
+// CountWindow returns a Window which covers the given amount of points.
+func CountWindow(points int64, align WindowAlign) Window {
+	return Window{
+		Unit:  WindowPoints,
+		Size:  points,
+		Align: align,
+	}
+}
+
+// Validate checks if the unit and alignment are valid and the size is positive.
+func (w Window) Validate() error {
+	if !w.Unit.Valid() {
+		return fmt.Errorf("invalid window unit: %d", w.Unit)
+	}
+
+	if !w.Align.Valid() {
+		return fmt.Errorf("invalid window alignment: %d", w.Align)
+	}
+
+	if w.Size <= 0 {
+		return fmt.Errorf("window size must be positive: %d", w.Size)
+	}
+
+	return nil
+}
+
+// Rolling applies the given AggregateFunc on a moving window and returns a point for each input point with the same
+// X value. Example using CountWindow(3, WindowRight) and AvgY:
+//  [(0|3), (600|6), (1200|9), (1800|3)]
+//  => [(0|3), (600|5), (1200|6), (1800|6)]
+// At the edges of the series, the window is truncated and contains fewer points, but it always contains at least
+// the evaluated point itself. Smoothing a noisy series before displaying is a typical use case, e.g.
+// p.Rolling(AvgY, TimeWindow(3600, WindowCenter)).Downscale(width). Panics, if the window is not valid, e.g. if
+// its size is not positive.
+//
+// Like M4, it expects that points are ordered ascended by X (==time). The result is undefined, if the dataset is not
+// sorted correctly.
+func (p Points) Rolling(f AggregateFunc, w Window) Points {
+	mustValidateWindow(w)
+	return Math.Rolling(p, f, w)
+}
+
+// RollingContext is like Rolling but the evaluation performs a Checkpoint regularly, e.g. after each chunk of
+// points, so that a rolling window over a year of raw data is aborted, as soon as the context is done or its budget
+// is exceeded.
+func (p Points) RollingContext(ctx context.Context, f AggregateFunc, w Window) Points {
+	mustValidateWindow(w)
+	return Math.RollingContext(ctx, p, f, w)
+}
+
+func mustValidateWindow(w Window) {
+	if err := w.Validate(); err != nil {
+		panic(fmt.Errorf("cannot roll window: %w", err))
+	}
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: © 2022 The mistral authors <github.com/worldiety/mistral.git/lib/go/dsl/AUTHORS>
+// SPDX-License-Identifier: BSD-2-Clause
+
+package miel
+
+import "testing"
+
+func TestWindow_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		w    Window
+		ok   bool
+	}{
+		{"time", TimeWindow(3600, WindowCenter), true},
+		{"count", CountWindow(3, WindowRight), true},
+		{"zero-size", CountWindow(0, WindowRight), false},
+		{"negative-size", TimeWindow(-1, WindowLeft), false},
+		{"no-unit", Window{Size: 3, Align: WindowRight}, false},
+		{"no-align", Window{Unit: WindowPoints, Size: 3}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.w.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate() = %v, want ok = %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPoints_Rolling_Invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+
+	Points{{X: 1, Y: 1}}.Rolling(AvgY, CountWindow(0, WindowRight))
+}